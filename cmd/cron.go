@@ -24,11 +24,19 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/provider/file"
 	"github.com/lightglitch/seekerr/utils/logger"
+	"github.com/lightglitch/seekerr/utils/metrics"
 	"github.com/robfig/cron/v3"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 )
 
 // cronCmd represents the cron command
@@ -44,18 +52,53 @@ var cronCmd = &cobra.Command{
 		spec := viper.GetString("cron")
 		schedule, err := cron.ParseStandard(spec)
 		if err != nil {
-			fmt.Printf("Invalid cron schedule: %s, %s \n", err)
+			fmt.Printf("Invalid cron schedule: %s, %s \n", spec, err)
 			return
 		}
 		c := cron.New(cron.WithLogger(zeroLogger {
 			logger: logger.GetLogger(),
 		}))
 
+		if listen := viper.GetString("metrics.listen"); listen != "" {
+			go serveMetrics(listen)
+		}
+
+		// the dispatcher lives across the runs so the events held by the
+		// quiet hours are sent when the window ends
+		restyClient := newRestyClient()
+		dispatcher := newDispatcher(restyClient)
+
+		// the scheduled and the watched imports run one at a time
+		var mutex sync.Mutex
+		importList := func(name string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if _, err := runImport(restyClient, dispatcher, name); err != nil {
+				logger.GetLogger().Error().Err(err).Msg("Invalid configuration")
+			}
+		}
+
 		c.Schedule(schedule, cron.FuncJob(func() {
-			importCmd.Run(cmd, args)
+			importList(listName)
 		}))
 
-		c.Run()
+		watcher := watchLists()
+		if watcher != nil {
+			go watcher.Run(importList)
+		}
+
+		c.Start()
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+
+		logger.GetLogger().Info().Msg("Stopping cron")
+		<-c.Stop().Done()
+		if watcher != nil {
+			_ = watcher.Close()
+		}
+		dispatcher.Close()
 	},
 }
 
@@ -75,6 +118,44 @@ func init() {
 	viper.BindPFlag("cron", cronCmd.Flags().Lookup("schedule"))
 }
 
+// watchLists watches the file lists configured with watch, nil when there
+// are none.
+func watchLists() *file.Watcher {
+	lists := viper.Sub("importer.lists")
+	if lists == nil {
+		return nil
+	}
+
+	var watcher *file.Watcher
+	for name := range lists.AllSettings() {
+		list := lists.Sub(name)
+		if list == nil || list.GetString("type") != provider.FILE || !list.GetBool("watch") {
+			continue
+		}
+		if watcher == nil {
+			var err error
+			if watcher, err = file.NewWatcher(logger.GetLogger()); err != nil {
+				logger.GetLogger().Error().Err(err).Msg("Can't watch the file lists")
+				return nil
+			}
+		}
+		if err := watcher.Add(name, list.GetString("url")); err != nil {
+			logger.GetLogger().Error().Err(err).Msgf("Can't watch list '%s'", name)
+		}
+	}
+	return watcher
+}
+
+func serveMetrics(listen string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	logger.GetLogger().Info().Str("listen", listen).Msg("Serving metrics")
+	if err := http.ListenAndServe(listen, mux); err != nil {
+		logger.GetLogger().Error().Err(err).Msg("Serving metrics")
+	}
+}
+
 type zeroLogger struct {
 	logger *zerolog.Logger
 }
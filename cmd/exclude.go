@@ -0,0 +1,87 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package cmd
+
+import (
+	"github.com/lightglitch/seekerr/services/radarr"
+	"github.com/lightglitch/seekerr/utils/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+)
+
+var excludeCmd = &cobra.Command{
+	Use:   "exclude <imdb or tmdb id>...",
+	Short: "Add movies to the radarr import exclusions.",
+	Long: `Add movies to the radarr import exclusions.
+
+The movies are identified by the imdb id, like tt0133093, or the tmdb id, like
+603. Excluded movies aren't added by seekerr or by the radarr lists.`,
+	Args: cobra.MinimumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		logger.InitLogger()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if !viper.IsSet("services.radarr") {
+			logger.GetLogger().Error().Msg("Missing radarr configuration")
+			os.Exit(EXIT_CONFIG_ERROR)
+		}
+		client := radarr.NewClient(viper.Sub("services.radarr"), logger.GetLogger(), newRestyClient())
+		if client == nil {
+			logger.GetLogger().Error().Msg("Can't create the radarr client")
+			os.Exit(EXIT_CONFIG_ERROR)
+		}
+
+		failed := 0
+		for _, id := range args {
+			var movie *radarr.Movie
+			var err error
+			if strings.HasPrefix(id, "tt") {
+				movie, err = client.LookupMovieByImdb(id)
+			} else {
+				movie, err = client.LookupMovieByTmdb(id)
+			}
+			if err == nil {
+				err = client.AddExclusion(movie)
+			}
+			if err != nil {
+				logger.GetLogger().Error().Err(err).Str("id", id).Msg("Excluding movie")
+				failed++
+				continue
+			}
+			logger.GetLogger().Info().Str("id", id).Msgf("Movie '%s (%d)' added to radarr exclusions.", movie.Title, movie.Year)
+		}
+
+		if failed == len(args) {
+			os.Exit(EXIT_FAILED)
+		} else if failed > 0 {
+			os.Exit(EXIT_PARTIAL_FAILED)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(excludeCmd)
+}
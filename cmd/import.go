@@ -23,11 +23,25 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-resty/resty/v2"
 	"github.com/lightglitch/seekerr/importer"
+	"github.com/lightglitch/seekerr/importer/history"
+	"github.com/lightglitch/seekerr/importer/state"
 	"github.com/lightglitch/seekerr/notification"
+	"github.com/lightglitch/seekerr/notification/apprise"
+	"github.com/lightglitch/seekerr/notification/discord"
+	"github.com/lightglitch/seekerr/notification/email"
 	"github.com/lightglitch/seekerr/notification/gotify"
+	"github.com/lightglitch/seekerr/notification/ntfy"
 	"github.com/lightglitch/seekerr/notification/slack"
+	"github.com/lightglitch/seekerr/notification/telegram"
+	"github.com/lightglitch/seekerr/notification/webhook"
 	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/provider/file"
+	"github.com/lightglitch/seekerr/provider/generic"
 	"github.com/lightglitch/seekerr/provider/imdb"
 	"github.com/lightglitch/seekerr/provider/rss"
 	traktprovider "github.com/lightglitch/seekerr/provider/trakt"
@@ -38,64 +52,211 @@ import (
 	"github.com/lightglitch/seekerr/utils/http"
 	"github.com/lightglitch/seekerr/utils/logger"
 	"github.com/spf13/viper"
+	"os"
 
 	"github.com/spf13/cobra"
 )
 
-var listName string
+const (
+	EXIT_OK             = 0
+	EXIT_CONFIG_ERROR   = 2
+	EXIT_PARTIAL_FAILED = 3
+	EXIT_FAILED         = 4
+)
+
+var (
+	listName     string
+	importOutput string
+)
 
 var importCmd = &cobra.Command{
 	Use:   "import",
 	Short: "Import the movies found in the lists to radarr.",
-	Long:  ``,
+	Long: `Import the movies found in the lists to radarr.
+
+Exit codes:
+  0  all lists were imported
+  2  invalid configuration
+  3  some lists or movies failed
+  4  all lists failed`,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		initConfig()
+		if importOutput == "json" {
+			// keep stdout clean for the summary
+			viper.Set("logger.stderr", true)
+		}
 		logger.InitLogger()
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		restyClient := newRestyClient()
+		dispatcher := newDispatcher(restyClient)
+		run, err := runImport(restyClient, dispatcher, listName)
+		dispatcher.Close()
+		if err != nil {
+			logger.GetLogger().Error().Err(err).Msg("Invalid configuration")
+			os.Exit(EXIT_CONFIG_ERROR)
+		}
+
+		summary := run.Summary()
+		if importOutput == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.SetEscapeHTML(false)
+			_ = encoder.Encode(summary)
+		}
 
-		if viper.ConfigFileUsed() != "" {
-			var restyConfig *viper.Viper = nil
-			if viper.IsSet("services.resty") {
-				restyConfig = viper.Sub("services.resty")
-			}
-			restyClient := http.GetRestyClient(restyConfig)
-
-			radarr := radarr.NewClient(viper.Sub("services.radarr"), logger.GetLogger(), restyClient)
-			omdb := omdb.NewClient(viper.Sub("services.omdb"), logger.GetLogger(), restyClient)
-			gessit := guessit.NewClient(viper.Sub("services.guessIt"), logger.GetLogger(), restyClient)
-			trakt := trakt.NewClient(viper.Sub("services.trakt"), logger.GetLogger(), restyClient)
-
-			registry := provider.NewProviderRegistry()
-
-			registry.RegisterProvider(provider.RSS, rss.NewProvider(gessit, logger.GetLogger(), restyClient))
-			registry.RegisterProvider(provider.IMDB, imdb.NewProvider(logger.GetLogger(), restyClient))
-			registry.RegisterProvider(provider.TRAKT, traktprovider.NewProvider(trakt, logger.GetLogger()))
-
-			dispatcher := notification.NewNotificationDispatcher(logger.GetLogger())
-
-			if viper.IsSet("notifications.gotify") {
-				dispatcher.RegisterAgent(gotify.NewGotifyAgent(viper.Sub("notifications.gotify"), logger.GetLogger(), restyClient))
-			}
-			if viper.IsSet("notifications.slack") {
-				dispatcher.RegisterAgent(slack.NewSlackAgent(viper.Sub("notifications.slack"), logger.GetLogger(), restyClient))
-			}
-
-			config := viper.Sub("importer")
-			if viper.IsSet("revision") {
-				config.Set("revision", viper.Get("revision"))
-			}
-			importer := importer.NewImporter(config, logger.GetLogger(), radarr, omdb, registry, dispatcher)
-
-			if listName != "" && listName != "all" {
-				importer.ProcessList(listName)
-			} else {
-				importer.ProcessLists()
-			}
+		switch summary.Status {
+		case history.STATUS_PARTIAL:
+			os.Exit(EXIT_PARTIAL_FAILED)
+		case history.STATUS_FAILED:
+			os.Exit(EXIT_FAILED)
 		}
 	},
 }
 
+func newRestyClient() *resty.Client {
+	var restyConfig *viper.Viper = nil
+	if viper.IsSet("services.resty") {
+		restyConfig = viper.Sub("services.resty")
+	}
+	return http.GetRestyClient(restyConfig)
+}
+
+// registerAgent registers the agent with the delivery options of its config.
+func registerAgent(dispatcher *notification.Dispatcher, agent notification.Agent, config *viper.Viper) {
+	options, err := notification.NewAgentOptions(config)
+	if err != nil {
+		logger.GetLogger().Error().Err(err).Str("agent", agent.Name()).Msg("Invalid notification options")
+		return
+	}
+	dispatcher.RegisterAgent(agent, options)
+}
+
+// newDispatcher creates the dispatcher with the configured agents, it must be
+// closed to send the pending digests and keep the held events.
+func newDispatcher(restyClient *resty.Client) *notification.Dispatcher {
+	dispatcher := notification.NewNotificationDispatcher(viper.Sub("notifications"), logger.GetLogger())
+
+	if viper.IsSet("notifications.gotify") {
+		if agent := gotify.NewGotifyAgent(viper.Sub("notifications.gotify"), logger.GetLogger(), restyClient); agent != nil {
+			registerAgent(dispatcher, agent, viper.Sub("notifications.gotify"))
+		}
+	}
+	if viper.IsSet("notifications.slack") {
+		if agent := slack.NewSlackAgent(viper.Sub("notifications.slack"), logger.GetLogger(), restyClient); agent != nil {
+			registerAgent(dispatcher, agent, viper.Sub("notifications.slack"))
+		}
+	}
+	if viper.IsSet("notifications.discord") {
+		if agent := discord.NewDiscordAgent(viper.Sub("notifications.discord"), logger.GetLogger(), restyClient); agent != nil {
+			registerAgent(dispatcher, agent, viper.Sub("notifications.discord"))
+		}
+	}
+	if viper.IsSet("notifications.telegram") {
+		if agent := telegram.NewTelegramAgent(viper.Sub("notifications.telegram"), logger.GetLogger(), restyClient); agent != nil {
+			registerAgent(dispatcher, agent, viper.Sub("notifications.telegram"))
+		}
+	}
+	if viper.IsSet("notifications.email") {
+		if agent := email.NewEmailAgent(viper.Sub("notifications.email"), logger.GetLogger()); agent != nil {
+			registerAgent(dispatcher, agent, viper.Sub("notifications.email"))
+		}
+	}
+	if viper.IsSet("notifications.ntfy") {
+		if agent := ntfy.NewNtfyAgent(viper.Sub("notifications.ntfy"), logger.GetLogger(), restyClient); agent != nil {
+			registerAgent(dispatcher, agent, viper.Sub("notifications.ntfy"))
+		}
+	}
+	if viper.IsSet("notifications.apprise") {
+		if agent := apprise.NewAppriseAgent(viper.Sub("notifications.apprise"), logger.GetLogger(), restyClient); agent != nil {
+			registerAgent(dispatcher, agent, viper.Sub("notifications.apprise"))
+		}
+	}
+	for name := range viper.GetStringMap("notifications.webhook") {
+		config := viper.Sub("notifications.webhook." + name)
+		if agent := webhook.NewWebhookAgent(name, config, logger.GetLogger(), restyClient); agent != nil {
+			registerAgent(dispatcher, agent, config)
+		}
+	}
+
+	return dispatcher
+}
+
+// newImporter creates the importer with the configured services and providers.
+func newImporter(restyClient *resty.Client, dispatcher *notification.Dispatcher) (*importer.Importer, error) {
+
+	if viper.ConfigFileUsed() == "" {
+		return nil, errors.New("missing configuration file")
+	}
+
+	if !viper.IsSet("services.radarr") || !viper.IsSet("services.omdb") {
+		return nil, errors.New("missing radarr or omdb configuration")
+	}
+
+	radarr := radarr.NewClient(viper.Sub("services.radarr"), logger.GetLogger(), restyClient)
+	if radarr == nil {
+		return nil, errors.New("can't create the radarr client")
+	}
+	omdb := omdb.NewClient(viper.Sub("services.omdb"), logger.GetLogger(), restyClient)
+	if omdb == nil {
+		return nil, errors.New("can't create the omdb client")
+	}
+	gessit := guessit.NewClient(viper.Sub("services.guessIt"), logger.GetLogger(), restyClient)
+	var traktClient *trakt.Client
+	if viper.IsSet("services.trakt") {
+		traktClient = trakt.NewClient(viper.Sub("services.trakt"), logger.GetLogger(), restyClient)
+	}
+
+	registry := provider.NewProviderRegistry()
+
+	registry.RegisterProvider(provider.RSS, rss.NewProvider(gessit, logger.GetLogger(), restyClient))
+	registry.RegisterProvider(provider.IMDB, imdb.NewProvider(logger.GetLogger(), restyClient))
+	registry.RegisterProvider(provider.TRAKT, traktprovider.NewProvider(traktClient, logger.GetLogger()))
+	registry.RegisterProvider(provider.JSON, generic.NewJsonProvider(logger.GetLogger(), restyClient))
+	registry.RegisterProvider(provider.CSV, generic.NewCsvProvider(logger.GetLogger(), restyClient))
+	registry.RegisterProvider(provider.FILE, file.NewProvider(logger.GetLogger()))
+
+	config := viper.Sub("importer")
+	if config == nil {
+		return nil, errors.New("missing importer configuration")
+	}
+	if viper.IsSet("revision") {
+		config.Set("revision", viper.Get("revision"))
+	}
+	store := state.NewStore(viper.Sub("state"), logger.GetLogger())
+	if err := store.Load(); err != nil {
+		return nil, fmt.Errorf("loading lists state: %w", err)
+	}
+
+	return importer.NewImporter(config, logger.GetLogger(), radarr, omdb, registry, dispatcher, store)
+}
+
+// runImport imports the list, or all the lists, and saves the run in the history,
+// the error is only returned when the import can't start.
+func runImport(restyClient *resty.Client, dispatcher *notification.Dispatcher, name string) (*history.Run, error) {
+	importer, err := newImporter(restyClient, dispatcher)
+	if err != nil {
+		return nil, err
+	}
+
+	var run *history.Run
+	if name != "" && name != "all" {
+		if run, err = importer.ProcessList(name); err != nil {
+			return nil, err
+		}
+	} else {
+		run = importer.ProcessLists()
+	}
+
+	store := history.NewStore(viper.Sub("history"), logger.GetLogger())
+	if err := store.Save(run); err != nil {
+		logger.GetLogger().Error().Err(err).Msg("Saving run history")
+	}
+
+	logger.GetLogger().Info().Str("Status", run.Status()).Msg("Finish import.")
+	return run, nil
+}
+
 func init() {
 	rootCmd.AddCommand(importCmd)
 
@@ -110,5 +271,6 @@ func init() {
 	// importCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	importCmd.Flags().StringVarP(&listName, "list", "l", "all", "The name of the list to import")
 	importCmd.Flags().BoolP("revision", "r", false, "Notify me about movies that are not approved but match revision rules")
+	importCmd.Flags().StringVarP(&importOutput, "output", "o", "text", "Output format: text or json, json prints the summary of the run to stdout")
 	viper.BindPFlag("revision", importCmd.Flags().Lookup("revision"))
 }
@@ -0,0 +1,76 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package cmd
+
+import (
+	"github.com/lightglitch/seekerr/notification"
+	"github.com/lightglitch/seekerr/server"
+	"github.com/lightglitch/seekerr/utils/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+)
+
+var serveListen string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the approved movies of each list for radarr to import.",
+	Long: `Serve the approved movies of each list for radarr to import.
+
+Each list is available in /lists/<name> in the format of the radarr Custom
+and StevenLu lists, the movies are the ones approved by the list rules but
+they aren't added to radarr. The metrics are also available in /metrics.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		logger.InitLogger()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		// the lists don't send notifications
+		dispatcher := notification.NewNotificationDispatcher(nil, logger.GetLogger())
+		importer, err := newImporter(newRestyClient(), dispatcher)
+		if err != nil {
+			logger.GetLogger().Error().Err(err).Msg("Invalid configuration")
+			os.Exit(EXIT_CONFIG_ERROR)
+		}
+
+		config := viper.Sub("server")
+		if config == nil {
+			config = viper.New()
+		}
+		if serveListen != "" {
+			config.Set("listen", serveListen)
+		}
+
+		if err := server.NewServer(config, logger.GetLogger(), importer).ListenAndServe(); err != nil {
+			logger.GetLogger().Error().Err(err).Msg("Serving lists")
+			os.Exit(EXIT_FAILED)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", "", "Address to listen, like :8090")
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lightglitch/seekerr/importer/history"
+	"github.com/lightglitch/seekerr/utils/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	statsSince  string
+	statsList   string
+	statsOutput string
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the statistics of the previous imports.",
+	Long:  ``,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		logger.InitLogger()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		window, err := parseWindow(statsSince)
+		if err != nil {
+			fmt.Printf("Invalid time window: %s, %s \n", statsSince, err)
+			os.Exit(1)
+		}
+
+		to := time.Now().UTC()
+		from := to.Add(-window)
+
+		store := history.NewStore(viper.Sub("history"), logger.GetLogger())
+		runs, err := store.Load(from)
+		if err != nil {
+			fmt.Printf("Can't load run history: %s \n", err)
+			os.Exit(1)
+		}
+
+		if statsList != "" && statsList != "all" {
+			for _, run := range runs {
+				for name := range run.Lists {
+					if !strings.EqualFold(name, statsList) {
+						delete(run.Lists, name)
+					}
+				}
+			}
+		}
+
+		report := history.NewReport(runs, from, to)
+
+		switch statsOutput {
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.SetEscapeHTML(false)
+			_ = encoder.Encode(report)
+		default:
+			printReport(report)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringVarP(&statsSince, "since", "s", "7d", "Time window of the statistics, e.g. 24h, 7d, 30d")
+	statsCmd.Flags().StringVarP(&statsList, "list", "l", "all", "The name of the list to show")
+	statsCmd.Flags().StringVarP(&statsOutput, "output", "o", "table", "Output format: table or json")
+}
+
+// parseWindow accepts golang durations plus a days suffix.
+func parseWindow(window string) (time.Duration, error) {
+	if strings.HasSuffix(window, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(window, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(window)
+}
+
+func printReport(report *history.Report) {
+	fmt.Printf("Runs from %s to %s: %d\n\n", report.From.Format(time.RFC3339), report.To.Format(time.RFC3339), report.Runs)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LIST\tTYPE\tRUNS\tFETCHED\tEXISTING\tEXCLUDED\tAUTOEXCLUDED\tREJECTED\tREVISION\tAPPROVED\tADDED\tREMOVED\tERRORS")
+	for _, list := range report.Lists {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n",
+			list.Name, list.Type, list.Runs, list.Fetched, list.SkippedExisting, list.SkippedExcluded, list.AutoExcluded,
+			list.Rejected, list.Revision, list.Approved, list.Added, list.Removed, list.Errors)
+	}
+	w.Flush()
+
+	fmt.Println()
+
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REJECTIONS\tRULE")
+	for _, rule := range report.Rules {
+		fmt.Fprintf(w, "%d\t%s\n", rule.Rejections, rule.Rule)
+	}
+	w.Flush()
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lightglitch/seekerr/importer/identity"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/services/omdb"
+	"github.com/lightglitch/seekerr/services/radarr"
+	"sort"
+	"strings"
+)
+
+// ApprovedMovie is a list item approved by the rules, the movie is nil when
+// it can't be found in radarr.
+type ApprovedMovie struct {
+	Item  provider.ListItem
+	Movie *radarr.Movie
+}
+
+// Lists returns the names of the configured lists.
+func (i *Importer) Lists() []string {
+	names := []string{}
+	for name := range i.getListsConfigurations() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApprovedItems returns the movies of the list approved by the rules, with
+// the same providers and enrichment of the import, but without checking or
+// adding them to radarr, so radarr can import the list by itself.
+func (i *Importer) ApprovedItems(listName string) ([]ApprovedMovie, error) {
+	config, ok := i.getListsConfigurations()[strings.ToLower(listName)]
+	if !ok {
+		return nil, fmt.Errorf("can't find the configuration for list '%s'", listName)
+	}
+
+	provider, ok := i.registry.GetProvider(config.Type)
+	if !ok {
+		return nil, fmt.Errorf("invalid list type '%s'", config.Type)
+	}
+	if err := i.validator.InitRules(config); err != nil {
+		return nil, fmt.Errorf("invalid list rules: %w", err)
+	}
+
+	items, err := provider.GetItems(config)
+	if err != nil {
+		return nil, err
+	}
+
+	approved := []ApprovedMovie{}
+	seen := identity.NewIndex()
+	for _, item := range items {
+		confident, err := i.resolveItem(&item)
+		if err != nil {
+			i.logger.Error().Err(err).Msgf("Resolving movie '%s (%d)'.", item.Title, item.Year)
+			continue
+		}
+		if !confident {
+			continue
+		}
+		// like the import, the movies not found in omdb are rejected and the
+		// ones with errors skipped
+		if err := i.populateExtraInfo(&item); errors.Is(err, omdb.ErrNotFound) {
+			i.logger.Debug().Msgf("Movie '%s (%d)' not found in omdb.", item.Title, item.Year)
+			continue
+		} else if err != nil {
+			i.logger.Error().Err(err).Msgf("Fetching omdb info of '%s (%d)'.", item.Title, item.Year)
+			continue
+		}
+
+		key := identity.NewKey(item.Tmdb, item.Imdb, item.Title, item.Year)
+		if seen.Contains(key) || !i.validator.IsItemApproved(&item) {
+			continue
+		}
+		if excluded, _ := i.validator.IsItemAutoExcluded(&item); excluded {
+			continue
+		}
+		seen.Add(key)
+
+		movie, err := i.lookupMovie(&item)
+		if err != nil {
+			i.logger.Error().Err(err).Msgf("Looking movie '%s (%d)' in radarr.", item.Title, item.Year)
+			movie = nil
+		}
+		approved = append(approved, ApprovedMovie{Item: item, Movie: movie})
+	}
+
+	i.logger.Info().Int("Fetched", len(items)).Int("Approved", len(approved)).Msgf("Approved items of list '%s'.", listName)
+	return approved, nil
+}
@@ -0,0 +1,261 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	DEFAULT_FILE = "var/history/seekerr.runs.jsonl"
+
+	STATUS_SUCCESS = "success"
+	STATUS_PARTIAL = "partial"
+	STATUS_FAILED  = "failed"
+)
+
+type ListStats struct {
+	Type            string         `json:"type"`
+	Fetched         int            `json:"fetched"`
+	SkippedExisting int            `json:"skippedExisting"`
+	SkippedExcluded int            `json:"skippedExcluded"`
+	Evaluated       int            `json:"evaluated"`
+	AutoExcluded    int            `json:"autoExcluded"`
+	Rejected        int            `json:"rejected"`
+	Revision        int            `json:"revision"`
+	Approved        int            `json:"approved"`
+	Added           int            `json:"added"`
+	Removed         int            `json:"removed"`
+	Errors          int            `json:"errors"`
+	Rules           map[string]int `json:"rules,omitempty"`
+	Failed          bool           `json:"failed,omitempty"`
+	AddedMovies     []string       `json:"addedMovies,omitempty"`
+	RemovedMovies   []string       `json:"removedMovies,omitempty"`
+	ExcludedMovies  []string       `json:"excludedMovies,omitempty"`
+	ErrorMessages   []string       `json:"errorMessages,omitempty"`
+}
+
+func NewListStats(listType string) *ListStats {
+	return &ListStats{
+		Type:  listType,
+		Rules: map[string]int{},
+	}
+}
+
+func (s *ListStats) RejectedBy(rule string) {
+	s.Rejected++
+	if rule != "" {
+		s.Rules[rule]++
+	}
+}
+
+func (s *ListStats) AddedMovie(title string) {
+	s.Added++
+	s.AddedMovies = append(s.AddedMovies, title)
+}
+
+func (s *ListStats) RemovedMovie(title string) {
+	s.Removed++
+	s.RemovedMovies = append(s.RemovedMovies, title)
+}
+
+func (s *ListStats) AutoExcludedMovie(title string) {
+	s.AutoExcluded++
+	s.ExcludedMovies = append(s.ExcludedMovies, title)
+}
+
+func (s *ListStats) Error(err error) {
+	s.Errors++
+	if err != nil {
+		s.ErrorMessages = append(s.ErrorMessages, err.Error())
+	}
+}
+
+// Fail marks the list as failed, used when the list couldn't be processed at all.
+func (s *ListStats) Fail(err error) {
+	s.Failed = true
+	s.Error(err)
+}
+
+// HasFailed is true when the list couldn't be processed or every evaluated
+// item had errors, the existing, excluded and repeated items aren't evaluated.
+func (s *ListStats) HasFailed() bool {
+	return s.Failed || (s.Evaluated > 0 && s.Errors >= s.Evaluated)
+}
+
+type Run struct {
+	Start time.Time             `json:"start"`
+	End   time.Time             `json:"end"`
+	Lists map[string]*ListStats `json:"lists"`
+}
+
+func NewRun() *Run {
+	return &Run{
+		Start: time.Now().UTC(),
+		Lists: map[string]*ListStats{},
+	}
+}
+
+func (r *Run) Finish() {
+	r.End = time.Now().UTC()
+}
+
+func (r *Run) Approved() int {
+	count := 0
+	for _, stats := range r.Lists {
+		count += stats.Approved
+	}
+	return count
+}
+
+func (r *Run) Added() int {
+	count := 0
+	for _, stats := range r.Lists {
+		count += stats.Added
+	}
+	return count
+}
+
+func (r *Run) Status() string {
+	failed, withErrors := 0, 0
+	for _, stats := range r.Lists {
+		if stats.HasFailed() {
+			failed++
+		} else if stats.Errors > 0 {
+			withErrors++
+		}
+	}
+
+	if failed > 0 && failed == len(r.Lists) {
+		return STATUS_FAILED
+	}
+	if failed > 0 || withErrors > 0 {
+		return STATUS_PARTIAL
+	}
+	return STATUS_SUCCESS
+}
+
+type Summary struct {
+	Status   string                `json:"status"`
+	Start    time.Time             `json:"start"`
+	End      time.Time             `json:"end"`
+	Approved int                   `json:"approved"`
+	Added    int                   `json:"added"`
+	Errors   int                   `json:"errors"`
+	Lists    map[string]*ListStats `json:"lists"`
+}
+
+func (r *Run) Summary() Summary {
+	errors := 0
+	for _, stats := range r.Lists {
+		errors += stats.Errors
+	}
+
+	return Summary{
+		Status:   r.Status(),
+		Start:    r.Start,
+		End:      r.End,
+		Approved: r.Approved(),
+		Added:    r.Added(),
+		Errors:   errors,
+		Lists:    r.Lists,
+	}
+}
+
+func NewStore(config *viper.Viper, logger *zerolog.Logger) *Store {
+	file := DEFAULT_FILE
+	if config != nil && config.GetString("file") != "" {
+		file = config.GetString("file")
+	}
+
+	return &Store{
+		logger: logger.With().Str("Component", "History").Logger(),
+		file:   file,
+	}
+}
+
+// Store keeps the runs in a json lines file, one run per line.
+type Store struct {
+	logger zerolog.Logger
+	file   string
+}
+
+func (s *Store) Save(run *Run) error {
+	if err := os.MkdirAll(filepath.Dir(s.file), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	s.logger.Debug().Str("file", s.file).Msg("Saved run history")
+	return nil
+}
+
+// Load returns the runs started after since, ordered as they were saved.
+func (s *Store) Load(since time.Time) ([]Run, error) {
+	runs := []Run{}
+
+	f, err := os.Open(s.file)
+	if os.IsNotExist(err) {
+		return runs, nil
+	}
+	if err != nil {
+		return runs, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		run := Run{}
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			s.logger.Warn().Err(err).Msg("Skipping invalid run history line")
+			continue
+		}
+		if run.Start.Before(since) {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, scanner.Err()
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package history
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHasFailed(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    ListStats
+		expected bool
+	}{
+		{name: "existing items with one error", expected: false,
+			stats: ListStats{Fetched: 202, SkippedExisting: 200, Evaluated: 2, AutoExcluded: 1, Errors: 1}},
+		{name: "existing items without evaluated items", expected: false,
+			stats: ListStats{Fetched: 200, SkippedExisting: 150, SkippedExcluded: 50, Errors: 1}},
+		{name: "every evaluated item with errors", expected: true,
+			stats: ListStats{Fetched: 203, SkippedExisting: 200, Evaluated: 3, Errors: 3}},
+		{name: "some evaluated items with errors", expected: false,
+			stats: ListStats{Fetched: 10, Evaluated: 10, Rejected: 8, Errors: 2}},
+		{name: "no errors", expected: false,
+			stats: ListStats{Fetched: 10, Evaluated: 10, Approved: 2, Rejected: 8}},
+		{name: "empty list", expected: false},
+		{name: "failed list", expected: true, stats: ListStats{Failed: true, Errors: 1}},
+	}
+
+	for _, test := range tests {
+		if result := test.stats.HasFailed(); result != test.expected {
+			t.Errorf("%s: HasFailed() = %t, expected %t", test.name, result, test.expected)
+		}
+	}
+}
+
+func TestRunStatus(t *testing.T) {
+	run := NewRun()
+	existing := NewListStats("trakt")
+	existing.Fetched, existing.SkippedExisting, existing.Evaluated = 202, 200, 2
+	existing.RejectedBy("")
+	existing.Error(errors.New("looking movie in radarr"))
+	run.Lists["existing"] = existing
+	if status := run.Status(); status != STATUS_PARTIAL {
+		t.Errorf("Status() = %s, expected %s", status, STATUS_PARTIAL)
+	}
+
+	failed := NewListStats("imdb")
+	failed.Fail(errors.New("fetching list"))
+	run.Lists["failed"] = failed
+	if status := run.Status(); status != STATUS_PARTIAL {
+		t.Errorf("Status() = %s, expected %s", status, STATUS_PARTIAL)
+	}
+
+	delete(run.Lists, "existing")
+	if status := run.Status(); status != STATUS_FAILED {
+		t.Errorf("Status() = %s, expected %s", status, STATUS_FAILED)
+	}
+}
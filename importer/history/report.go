@@ -0,0 +1,112 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+type ListReport struct {
+	Name string `json:"name"`
+	Runs int    `json:"runs"`
+	ListStats
+}
+
+type RuleReport struct {
+	Rule       string `json:"rule"`
+	Rejections int    `json:"rejections"`
+}
+
+type Report struct {
+	From  time.Time    `json:"from"`
+	To    time.Time    `json:"to"`
+	Runs  int          `json:"runs"`
+	Lists []ListReport `json:"lists"`
+	Rules []RuleReport `json:"rules"`
+}
+
+// NewReport sums the runs per list and per rule. Lists are ordered by the
+// number of added movies and rules by the number of rejections.
+func NewReport(runs []Run, from time.Time, to time.Time) *Report {
+	lists := map[string]*ListReport{}
+	rules := map[string]int{}
+
+	for _, run := range runs {
+		for name, stats := range run.Lists {
+			list, ok := lists[name]
+			if !ok {
+				list = &ListReport{Name: name, ListStats: *NewListStats(stats.Type)}
+				lists[name] = list
+			}
+			list.Runs++
+			list.Type = stats.Type
+			list.Fetched += stats.Fetched
+			list.SkippedExisting += stats.SkippedExisting
+			list.SkippedExcluded += stats.SkippedExcluded
+			list.Evaluated += stats.Evaluated
+			list.AutoExcluded += stats.AutoExcluded
+			list.Rejected += stats.Rejected
+			list.Revision += stats.Revision
+			list.Approved += stats.Approved
+			list.Added += stats.Added
+			list.Removed += stats.Removed
+			list.Errors += stats.Errors
+
+			for rule, count := range stats.Rules {
+				list.Rules[rule] += count
+				rules[rule] += count
+			}
+		}
+	}
+
+	report := &Report{
+		From:  from,
+		To:    to,
+		Runs:  len(runs),
+		Lists: []ListReport{},
+		Rules: []RuleReport{},
+	}
+
+	for _, list := range lists {
+		report.Lists = append(report.Lists, *list)
+	}
+	sort.Slice(report.Lists, func(a, b int) bool {
+		if report.Lists[a].Added == report.Lists[b].Added {
+			return report.Lists[a].Name < report.Lists[b].Name
+		}
+		return report.Lists[a].Added > report.Lists[b].Added
+	})
+
+	for rule, count := range rules {
+		report.Rules = append(report.Rules, RuleReport{Rule: rule, Rejections: count})
+	}
+	sort.Slice(report.Rules, func(a, b int) bool {
+		if report.Rules[a].Rejections == report.Rules[b].Rejections {
+			return report.Rules[a].Rule < report.Rules[b].Rule
+		}
+		return report.Rules[a].Rejections > report.Rules[b].Rejections
+	})
+
+	return report
+}
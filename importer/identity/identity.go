@@ -0,0 +1,115 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package identity
+
+import (
+	"fmt"
+	"github.com/gosimple/slug"
+)
+
+// Key identifies a movie by its ids, the title and year are only used when
+// the ids can't be compared.
+type Key struct {
+	Tmdb  int
+	Imdb  string
+	Title string
+	Year  int
+}
+
+func NewKey(tmdb int, imdb string, title string, year int) Key {
+	return Key{
+		Tmdb:  tmdb,
+		Imdb:  imdb,
+		Title: NormalizeTitle(title),
+		Year:  year,
+	}
+}
+
+// NormalizeTitle ignores the case, accents and punctuation of the title.
+func NormalizeTitle(title string) string {
+	return slug.Make(title)
+}
+
+func (k Key) titleYear() string {
+	return fmt.Sprintf("%s-%d", k.Title, k.Year)
+}
+
+// conflicts is true when both keys have an id of the same type that differs,
+// like a remake with the same title.
+func (k Key) conflicts(other Key) bool {
+	if k.Tmdb != 0 && other.Tmdb != 0 && k.Tmdb != other.Tmdb {
+		return true
+	}
+	return k.Imdb != "" && other.Imdb != "" && k.Imdb != other.Imdb
+}
+
+func NewIndex() *Index {
+	return &Index{
+		tmdb:   map[int]Key{},
+		imdb:   map[string]Key{},
+		titles: map[string][]Key{},
+	}
+}
+
+// Index finds movies by tmdb or imdb id, falling back to the normalized title
+// and year when the movies don't have ids of the same type.
+type Index struct {
+	tmdb   map[int]Key
+	imdb   map[string]Key
+	titles map[string][]Key
+}
+
+func (x *Index) Add(key Key) {
+	if key.Tmdb != 0 {
+		x.tmdb[key.Tmdb] = key
+	}
+	if key.Imdb != "" {
+		x.imdb[key.Imdb] = key
+	}
+	if key.Title != "" {
+		x.titles[key.titleYear()] = append(x.titles[key.titleYear()], key)
+	}
+}
+
+func (x *Index) Find(key Key) (Key, bool) {
+	if found, ok := x.tmdb[key.Tmdb]; ok && key.Tmdb != 0 {
+		return found, true
+	}
+	if found, ok := x.imdb[key.Imdb]; ok && key.Imdb != "" {
+		return found, true
+	}
+	if key.Title == "" {
+		return Key{}, false
+	}
+	for _, found := range x.titles[key.titleYear()] {
+		if !found.conflicts(key) {
+			return found, true
+		}
+	}
+	return Key{}, false
+}
+
+func (x *Index) Contains(key Key) bool {
+	_, ok := x.Find(key)
+	return ok
+}
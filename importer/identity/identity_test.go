@@ -0,0 +1,69 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package identity
+
+import (
+	"testing"
+)
+
+func TestIndexFind(t *testing.T) {
+	index := NewIndex()
+	index.Add(NewKey(8337, "tt0076759", "Dune", 1984))
+	index.Add(NewKey(438631, "tt1160419", "Dune", 2021))
+	index.Add(NewKey(194, "tt0211915", "Amélie", 2001))
+	index.Add(NewKey(0, "", "The Thing", 1982))
+
+	tests := []struct {
+		name     string
+		key      Key
+		found    bool
+		expected Key
+	}{
+		{name: "same tmdb id", key: NewKey(438631, "", "", 0),
+			found: true, expected: NewKey(438631, "tt1160419", "Dune", 2021)},
+		{name: "imdb only item with tmdb and imdb key", key: NewKey(0, "tt1160419", "", 0),
+			found: true, expected: NewKey(438631, "tt1160419", "Dune", 2021)},
+		{name: "same title and year", key: NewKey(0, "", "Dune", 2021),
+			found: true, expected: NewKey(438631, "tt1160419", "Dune", 2021)},
+		{name: "same title and year with accents and case", key: NewKey(0, "", "AMELIE", 2001),
+			found: true, expected: NewKey(194, "tt0211915", "Amélie", 2001)},
+		{name: "same title with other year", key: NewKey(0, "", "Dune", 2000)},
+		{name: "same title and year with other tmdb id", key: NewKey(1, "", "Dune", 2021)},
+		{name: "same title and year with other imdb id", key: NewKey(0, "tt0000001", "Dune", 2021)},
+		{name: "remake with the title of a movie without ids", key: NewKey(60935, "tt0905372", "The Thing", 2011)},
+		{name: "movie without ids", key: NewKey(1091, "tt0084787", "The Thing", 1982),
+			found: true, expected: NewKey(0, "", "The Thing", 1982)},
+		{name: "unknown movie", key: NewKey(603, "tt0133093", "The Matrix", 1999)},
+		{name: "empty key", key: Key{}},
+	}
+
+	for _, test := range tests {
+		found, ok := index.Find(test.key)
+		if ok != test.found || found != test.expected {
+			t.Errorf("%s: Find(%+v) = %+v %t, expected %+v %t", test.name, test.key, found, ok, test.expected, test.found)
+		}
+		if contains := index.Contains(test.key); contains != test.found {
+			t.Errorf("%s: Contains(%+v) = %t, expected %t", test.name, test.key, contains, test.found)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package identity
+
+import (
+	"github.com/spf13/viper"
+	"unicode/utf8"
+)
+
+const (
+	DEFAULT_MATCH_THRESHOLD    = 0.85
+	DEFAULT_REVISION_THRESHOLD = 0.6
+	// candidates of different movies with closer scores are ambiguous
+	AMBIGUITY_MARGIN = 0.05
+
+	TITLE_WEIGHT = 0.7
+	YEAR_WEIGHT  = 0.2
+	TYPE_WEIGHT  = 0.1
+)
+
+// Candidate is a movie found by the title search, the first title is the main
+// one and the others are the original and alternative titles.
+type Candidate struct {
+	Titles []string
+	Year   int
+	Imdb   string
+	Tmdb   int
+	Type   string
+}
+
+func (c Candidate) sameMovie(other Candidate) bool {
+	if c.Imdb != "" && other.Imdb != "" {
+		return c.Imdb == other.Imdb
+	}
+	if c.Tmdb != 0 && other.Tmdb != 0 {
+		return c.Tmdb == other.Tmdb
+	}
+	return c.Year == other.Year && len(c.Titles) > 0 && len(other.Titles) > 0 &&
+		NormalizeTitle(c.Titles[0]) == NormalizeTitle(other.Titles[0])
+}
+
+type Match struct {
+	Candidate Candidate
+	Score     float64
+	// false when the score is below the match threshold or another movie
+	// has a similar score
+	Confident bool
+}
+
+func levenshtein(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func min(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
+
+// TitleSimilarity is 1 for the same normalized titles and 0 for completely
+// different ones.
+func TitleSimilarity(a string, b string) float64 {
+	a, b = NormalizeTitle(a), NormalizeTitle(b)
+	if a == b {
+		return 1
+	}
+	length := utf8.RuneCountInString(a)
+	if other := utf8.RuneCountInString(b); other > length {
+		length = other
+	}
+	return 1 - float64(levenshtein([]rune(a), []rune(b)))/float64(length)
+}
+
+// Score is the confidence, between 0 and 1, of the candidate being the movie
+// with the title and year, the year is ignored when zero.
+func Score(title string, year int, candidate Candidate) float64 {
+	similarity := 0.0
+	for _, candidateTitle := range candidate.Titles {
+		if value := TitleSimilarity(title, candidateTitle); value > similarity {
+			similarity = value
+		}
+	}
+	score := TITLE_WEIGHT * similarity
+
+	diff := year - candidate.Year
+	switch {
+	case year == 0 || candidate.Year == 0:
+		score += YEAR_WEIGHT / 2
+	case diff == 0:
+		score += YEAR_WEIGHT
+	case diff == 1 || diff == -1:
+		score += YEAR_WEIGHT / 2
+	}
+
+	if candidate.Type == "" || candidate.Type == "movie" {
+		score += TYPE_WEIGHT
+	}
+	return score
+}
+
+// NewMatcher reads the thresholds of the config, it may be nil to use the
+// defaults.
+func NewMatcher(config *viper.Viper) *Matcher {
+	matcher := &Matcher{
+		MatchThreshold:    DEFAULT_MATCH_THRESHOLD,
+		RevisionThreshold: DEFAULT_REVISION_THRESHOLD,
+	}
+	if config != nil {
+		if config.IsSet("matchThreshold") {
+			matcher.MatchThreshold = config.GetFloat64("matchThreshold")
+		}
+		if config.IsSet("revisionThreshold") {
+			matcher.RevisionThreshold = config.GetFloat64("revisionThreshold")
+		}
+	}
+	return matcher
+}
+
+// Matcher picks the candidate of a title search, the matches above the
+// match threshold are confident and the ones above the revision threshold
+// need a revision.
+type Matcher struct {
+	MatchThreshold    float64
+	RevisionThreshold float64
+}
+
+// Best returns the candidate with the highest score, false when none is above
+// the revision threshold.
+func (m *Matcher) Best(title string, year int, candidates []Candidate) (Match, bool) {
+	best := Match{Score: -1}
+	scores := make([]float64, len(candidates))
+	for index, candidate := range candidates {
+		if len(candidate.Titles) == 0 {
+			scores[index] = -1
+			continue
+		}
+		scores[index] = Score(title, year, candidate)
+		if scores[index] > best.Score {
+			best = Match{Candidate: candidate, Score: scores[index]}
+		}
+	}
+	if best.Score < m.RevisionThreshold {
+		return Match{}, false
+	}
+
+	runnerUp := 0.0
+	for index, candidate := range candidates {
+		if scores[index] > runnerUp && !candidate.sameMovie(best.Candidate) {
+			runnerUp = scores[index]
+		}
+	}
+
+	// the same movie can be found by several searches with different ids
+	for _, candidate := range candidates {
+		if candidate.sameMovie(best.Candidate) {
+			if best.Candidate.Imdb == "" {
+				best.Candidate.Imdb = candidate.Imdb
+			}
+			if best.Candidate.Tmdb == 0 {
+				best.Candidate.Tmdb = candidate.Tmdb
+			}
+		}
+	}
+
+	best.Confident = best.Score >= m.MatchThreshold && best.Score-runnerUp >= AMBIGUITY_MARGIN
+	return best, true
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package identity
+
+import (
+	"testing"
+)
+
+func TestMatcherBest(t *testing.T) {
+	amelie := Candidate{Titles: []string{"Le Fabuleux Destin d'Amélie Poulain", "Amélie"}, Year: 2001, Imdb: "tt0211915"}
+	spiderMan := Candidate{Titles: []string{"Spider-Man: No Way Home"}, Year: 2021, Imdb: "tt10872600"}
+	dune := Candidate{Titles: []string{"Dune"}, Year: 2021, Imdb: "tt1160419"}
+
+	tests := []struct {
+		name       string
+		title      string
+		year       int
+		candidates []Candidate
+		found      bool
+		imdb       string
+		tmdb       int
+		confident  bool
+	}{
+		{name: "accents", title: "Amelie", year: 2001, candidates: []Candidate{amelie},
+			found: true, imdb: "tt0211915", confident: true},
+		{name: "punctuation", title: "Spider Man No Way Home", year: 2021, candidates: []Candidate{spiderMan},
+			found: true, imdb: "tt10872600", confident: true},
+		{name: "alternate title", title: "Le fabuleux destin d'Amelie Poulain", year: 2001,
+			candidates: []Candidate{spiderMan, amelie}, found: true, imdb: "tt0211915", confident: true},
+		{name: "year off by one", title: "Dune", year: 2022, candidates: []Candidate{dune},
+			found: true, imdb: "tt1160419", confident: true},
+		{name: "year off by two", title: "Dune", year: 2023, candidates: []Candidate{dune},
+			found: true, imdb: "tt1160419", confident: false},
+		{name: "unknown year", title: "Dune", candidates: []Candidate{dune},
+			found: true, imdb: "tt1160419", confident: true},
+		{name: "movie before series", title: "Dune", year: 2021, found: true, imdb: "tt1160419", confident: true,
+			candidates: []Candidate{{Titles: []string{"Dune"}, Year: 2021, Imdb: "tt0142032", Type: "series"}, dune}},
+		{name: "only series", title: "Dune", year: 2021, found: true, imdb: "tt0142032", confident: false,
+			candidates: []Candidate{{Titles: []string{"Dune"}, Year: 2023, Imdb: "tt0142032", Type: "series"}}},
+		{name: "ambiguous", title: "Dune", year: 2021, found: true, imdb: "tt1160419", confident: false,
+			candidates: []Candidate{dune, {Titles: []string{"Dune"}, Year: 2021, Imdb: "tt0000001"}}},
+		{name: "same movie with other ids", title: "Dune", year: 2021, found: true, imdb: "tt1160419", tmdb: 438631, confident: true,
+			candidates: []Candidate{dune, {Titles: []string{"Dune"}, Year: 2021, Tmdb: 438631}}},
+		{name: "different title", title: "The Matrix", year: 1999, candidates: []Candidate{dune, amelie}},
+		{name: "no titles", title: "Dune", year: 2021, candidates: []Candidate{{Year: 2021, Imdb: "tt1160419"}}},
+		{name: "no candidates", title: "Dune", year: 2021},
+	}
+
+	matcher := NewMatcher(nil)
+	for _, test := range tests {
+		match, found := matcher.Best(test.title, test.year, test.candidates)
+		if found != test.found {
+			t.Errorf("%s: found = %t, expected %t", test.name, found, test.found)
+			continue
+		}
+		if !found {
+			continue
+		}
+		if match.Candidate.Imdb != test.imdb || match.Candidate.Tmdb != test.tmdb || match.Confident != test.confident {
+			t.Errorf("%s: matched %s %d confident %t with score %.2f, expected %s %d confident %t", test.name,
+				match.Candidate.Imdb, match.Candidate.Tmdb, match.Confident, match.Score, test.imdb, test.tmdb, test.confident)
+		}
+	}
+}
@@ -23,22 +23,42 @@
 package importer
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gosimple/slug"
+	"github.com/lightglitch/seekerr/importer/history"
+	"github.com/lightglitch/seekerr/importer/identity"
+	"github.com/lightglitch/seekerr/importer/state"
 	"github.com/lightglitch/seekerr/importer/validator"
 	"github.com/lightglitch/seekerr/notification"
 	"github.com/lightglitch/seekerr/provider"
 	"github.com/lightglitch/seekerr/services/omdb"
 	"github.com/lightglitch/seekerr/services/radarr"
+	"github.com/lightglitch/seekerr/utils/metrics"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 	"strconv"
 	"strings"
 )
 
+// RULE_LOW_CONFIDENCE is the rejection of the items matched by title with low
+// confidence.
+const RULE_LOW_CONFIDENCE = "low confidence title match"
+
+// RULE_NOT_IN_OMDB is the rejection of the items that omdb doesn't know.
+const RULE_NOT_IN_OMDB = "not found in omdb"
+
 func NewImporter(config *viper.Viper, logger *zerolog.Logger,
 	radarrClient *radarr.Client, omdbClient *omdb.Client,
-	registry *provider.Registry, dispatcher *notification.Dispatcher) *Importer {
+	registry *provider.Registry, dispatcher *notification.Dispatcher, state *state.Store) (*Importer, error) {
+
+	if radarrClient == nil {
+		return nil, errors.New("missing radarr client")
+	}
+	if omdbClient == nil {
+		return nil, errors.New("missing omdb client")
+	}
+
 	importer := &Importer{
 		logger:     logger.With().Str("Component", "Importer").Logger(),
 		config:     config,
@@ -46,14 +66,20 @@ func NewImporter(config *viper.Viper, logger *zerolog.Logger,
 		omdb:       omdbClient,
 		registry:   registry,
 		dispatcher: dispatcher,
+		state:      state,
 		validator:  validator.NewRuleValidatior(logger),
-		processed:  map[string]bool{},
-		added:      map[string]bool{},
-		excluded:   map[string]bool{},
+		matcher:    identity.NewMatcher(config.Sub("resolver")),
+		processed:  identity.NewIndex(),
+		existing:   identity.NewIndex(),
+		exclusions: identity.NewIndex(),
+		library:    map[int]radarr.Movie{},
+		lookups:    map[string]*radarr.Movie{},
 	}
 
-	importer.initCache()
-	return importer
+	if err := importer.initCache(); err != nil {
+		return nil, err
+	}
+	return importer, nil
 }
 
 type Importer struct {
@@ -63,37 +89,46 @@ type Importer struct {
 	omdb       *omdb.Client
 	registry   *provider.Registry
 	validator  *validator.RuleValidatior
+	matcher    *identity.Matcher
 	dispatcher *notification.Dispatcher
-	processed  map[string]bool
-	added      map[string]bool
-	excluded   map[string]bool
+	state      *state.Store
+	processed  *identity.Index
+	existing   *identity.Index
+	exclusions *identity.Index
+	// radarr movies by tmdb id
+	library map[int]radarr.Movie
+	// radarr lookups by imdb or tmdb id
+	lookups map[string]*radarr.Movie
 }
 
-func (i *Importer) initCache() {
+func (i *Importer) initCache() error {
 	movies, err := i.radarr.GetMovies()
 	if err != nil {
 		i.logger.Error().Err(err).Msg("Can't load radarr movies.")
+		return fmt.Errorf("loading radarr movies: %w", err)
 	}
 
 	if movies != nil {
 		i.logger.Info().Int("Count", len(*movies)).Msg("Init radarr cache")
 		for _, movie := range *movies {
-			i.added[movie.ImdbId] = true
+			i.existing.Add(identity.NewKey(movie.TmdbID, movie.ImdbId, movie.Title, movie.Year))
+			i.library[movie.TmdbID] = movie
 		}
 	}
 
 	excluded, err := i.radarr.GetExcludedMovies()
 	if err != nil {
 		i.logger.Error().Err(err).Msg("Can't load radarr excluded movies.")
+		return fmt.Errorf("loading radarr excluded movies: %w", err)
 	}
 
 	if excluded != nil {
 		i.logger.Info().Int("Count", len(*excluded)).Msg("Excluded movies in radarr")
 		for _, excluded := range *excluded {
-			i.excluded[excluded.MovieTitle] = true
-			i.excluded[fmt.Sprintf("tmdb:%d", excluded.TmdbID)] = true
+			i.exclusions.Add(identity.NewKey(excluded.TmdbID, "", excluded.MovieTitle, excluded.MovieYear))
 		}
 	}
+	return nil
 }
 
 func (i *Importer) getListsConfigurations() map[string]provider.ListConfig {
@@ -137,13 +172,14 @@ func (i *Importer) getListsConfigurations() map[string]provider.ListConfig {
 	return lists
 }
 
-func (i *Importer) populateExtraInfo(item *provider.ListItem) {
+func (i *Importer) populateExtraInfo(item *provider.ListItem) error {
 
 	var movieResult *omdb.Result
+	var err error
 	if item.Imdb != "" {
-		movieResult, _ = i.omdb.GetMovieById(item.Imdb, map[string]string{})
+		movieResult, err = i.omdb.GetMovieById(item.Imdb, map[string]string{})
 	} else {
-		movieResult, _ = i.omdb.GetMovieByTitle(item.Title, map[string]string{
+		movieResult, err = i.omdb.GetMovieByTitle(item.Title, map[string]string{
 			"y": strconv.Itoa(item.Year),
 		})
 	}
@@ -171,66 +207,159 @@ func (i *Importer) populateExtraInfo(item *provider.ListItem) {
 		}
 	}
 
+	return err
 }
 
-func (i *Importer) lookupMovie(item *provider.ListItem) (movieResult *radarr.Movie, err error) {
-	if item.Tmdb != 0 {
-		movieResult, err = i.radarr.LookupMovieByTmdb(strconv.Itoa(item.Tmdb))
-	} else {
-		movieResult, err = i.radarr.LookupMovieByImdb(item.Imdb)
+func (i *Importer) itemError(listName string, item *provider.ListItem, stats *history.ListStats, err error) {
+	i.logger.Error().Err(err).Msgf("Processing list item '%s (%d)'.", item.Title, item.Year)
+	stats.Error(err)
+	metrics.ItemDecision(listName, metrics.DECISION_ERROR)
+	i.dispatcher.SendEventItemError(listName, item, err)
+}
+
+func (i *Importer) listFailed(listName string, stats *history.ListStats, err error) {
+	i.logger.Error().Err(err).Msgf("Failed list '%s'.", listName)
+	stats.Fail(err)
+	i.dispatcher.SendEventListFailed(listName, err)
+}
+
+// autoExclude adds the item matched by the auto exclude rule to the radarr
+// exclusions, so neither seekerr nor the radarr lists add it.
+func (i *Importer) autoExclude(listName string, item *provider.ListItem, rule string, stats *history.ListStats) {
+	movieResult, err := i.lookupMovie(item)
+	if err != nil {
+		i.itemError(listName, item, stats, fmt.Errorf("looking movie in radarr: %w", err))
+		return
+	}
+	if err := i.radarr.AddExclusion(movieResult); err != nil {
+		i.itemError(listName, item, stats, fmt.Errorf("adding movie to radarr exclusions: %w", err))
+		return
 	}
-	return movieResult, err
+
+	i.logger.Info().Str("rule", rule).Msgf("[EXCLUDED] Movie '%s (%d)' added to radarr exclusions.", item.Title, item.Year)
+	i.exclusions.Add(identity.NewKey(movieResult.TmdbID, movieResult.ImdbId, movieResult.Title, movieResult.Year))
+	stats.AutoExcludedMovie(fmt.Sprintf("%s (%d)", movieResult.Title, movieResult.Year))
+	metrics.ItemDecision(listName, metrics.DECISION_AUTO_EXCLUDED)
 }
 
-func (i *Importer) processProviderItem(listName string, item *provider.ListItem) (approved bool, added bool) {
-	itemSlug := fmt.Sprintf("%s-%d", slug.Make(item.Title), item.Year)
+func (i *Importer) revisionItem(listName string, item *provider.ListItem, stats *history.ListStats) {
+	stats.Revision++
+	metrics.ItemDecision(listName, metrics.DECISION_REVISION)
+	if movieResult, err := i.lookupMovie(item); err == nil {
+		i.dispatcher.SendEventRevisionMovie(listName, item, movieResult)
+	} else {
+		i.itemError(listName, item, stats, fmt.Errorf("looking movie in radarr: %w", err))
+	}
+}
 
+func (i *Importer) processProviderItem(listName string, config provider.ListConfig, item *provider.ListItem, stats *history.ListStats) (approved bool, added bool) {
 	approved, added = false, false
-	_, processed := i.processed[item.Imdb]
-	_, exist := i.added[item.Imdb]
-	_, excluded := i.excluded[item.Title]
-	if !excluded {
-		_, excluded = i.excluded[fmt.Sprintf("tmdb:%d", item.Tmdb)]
+	confident, err := i.resolveItem(item)
+	if err != nil {
+		stats.Evaluated++
+		i.itemError(listName, item, stats, err)
+		return approved, added
 	}
+	itemSlug := fmt.Sprintf("%s-%d", slug.Make(item.Title), item.Year)
+
+	key := identity.NewKey(item.Tmdb, item.Imdb, item.Title, item.Year)
+	processed := i.processed.Contains(key)
+	exist := i.existing.Contains(key)
+	excluded := i.exclusions.Contains(key)
 	if exist {
 		i.logger.Info().Msgf("Movie already '%s (%d)' to radarr.", item.Title, item.Year)
+		stats.SkippedExisting++
+		metrics.ItemDecision(listName, metrics.DECISION_EXISTING)
 	}
 	if excluded {
 		i.logger.Info().Msgf("Movie '%s (%d)' excluded from radarr.", item.Title, item.Year)
+		stats.SkippedExcluded++
+		metrics.ItemDecision(listName, metrics.DECISION_EXCLUDED)
 	}
 	if !processed && !exist && !excluded {
+		stats.Evaluated++
 		i.logger.Info().Str("ImdbId", item.Imdb).Str("slug", itemSlug).
 			Msgf("Processing list item '%s (%d)'.", item.Title, item.Year)
-		i.processed[item.Imdb] = true
 
-		i.populateExtraInfo(item)
+		if err := i.populateExtraInfo(item); errors.Is(err, omdb.ErrNotFound) {
+			i.logger.Info().Msgf("Movie '%s (%d)' not found in omdb.", item.Title, item.Year)
+			stats.RejectedBy(RULE_NOT_IN_OMDB)
+			metrics.ItemDecision(listName, metrics.DECISION_REJECTED)
+			return approved, added
+		} else if err != nil {
+			// without the ratings the rules would reject it
+			i.itemError(listName, item, stats, fmt.Errorf("fetching omdb info: %w", err))
+			return approved, added
+		}
+
+		// the low confidence title matches are never added, and don't stop a
+		// confident match of the same movie
+		if !confident {
+			stats.RejectedBy(RULE_LOW_CONFIDENCE)
+			metrics.ItemDecision(listName, metrics.DECISION_REJECTED)
+			if i.config.GetBool("revision") {
+				i.revisionItem(listName, item, stats)
+			}
+			return approved, added
+		}
+		i.processed.Add(key)
+
+		if excluded, rule := i.validator.IsItemAutoExcluded(item); excluded {
+			i.autoExclude(listName, item, rule, stats)
+			return approved, added
+		}
 
 		// validate filters
-		if approved = i.validator.IsItemApproved(item); approved {
+		var rule string
+		if approved, rule = i.validator.ValidateItem(item); approved {
+			stats.Approved++
+			metrics.ItemDecision(listName, metrics.DECISION_APPROVED)
 
 			movieResult, err := i.lookupMovie(item)
 			if err == nil {
 				if err = i.radarr.AddMovie(movieResult); err == nil {
 					i.logger.Info().Msgf("[ADDED] Movie '%s (%d)' added to radarr.", item.Title, item.Year)
 					added = true
+					stats.AddedMovie(fmt.Sprintf("%s (%d)", movieResult.Title, movieResult.Year))
+					if config.Sync.Action != "" && i.state != nil {
+						i.state.Track(listName, movieResult)
+					}
+					metrics.ItemDecision(listName, metrics.DECISION_ADDED)
 					i.dispatcher.SendEventAddMovie(listName, item, movieResult)
 				} else {
-					i.logger.Error().Err(err).Msg("Adding movie to radarr")
+					i.itemError(listName, item, stats, fmt.Errorf("adding movie to radarr: %w", err))
 				}
 			} else {
-				i.logger.Error().Err(err).Msg("Looking movie in radarr")
+				i.itemError(listName, item, stats, fmt.Errorf("looking movie in radarr: %w", err))
 			}
-		} else if i.config.GetBool("revision") {
-			if revision := i.validator.IsItemForRevision(item); revision {
-				movieResult, _ := i.lookupMovie(item)
-				i.dispatcher.SendEventRevisionMovie(listName, item, movieResult)
+		} else {
+			stats.RejectedBy(rule)
+			metrics.ItemDecision(listName, metrics.DECISION_REJECTED)
+
+			if i.config.GetBool("revision") {
+				if revision := i.validator.IsItemForRevision(item); revision {
+					i.revisionItem(listName, item, stats)
+				}
 			}
 		}
 	}
 	return approved, added
 }
 
-func (i *Importer) processProviderList(listName string, config provider.ListConfig) (approvedCount int, addedCount int) {
+// markDone marks the items processed without errors in the lists that
+// support it, the items with errors are tried again on the next import.
+func (i *Importer) markDone(listName string, listProvider provider.ListProvider, config provider.ListConfig, items []provider.ListItem, stats *history.ListStats) {
+	marker, ok := listProvider.(provider.DoneMarker)
+	if !ok {
+		return
+	}
+	if err := marker.MarkDone(config, items); err != nil {
+		i.logger.Error().Err(err).Msgf("Marking the done items of list '%s'.", listName)
+		stats.Error(err)
+	}
+}
+
+func (i *Importer) processProviderList(listName string, config provider.ListConfig) *history.ListStats {
 
 	i.logger.Info().
 		Interface("Type", config.Type).
@@ -238,51 +367,73 @@ func (i *Importer) processProviderList(listName string, config provider.ListConf
 		Msgf("Processing list '%s'.", listName)
 
 	i.dispatcher.SendEventStartFeed(listName)
-	approvedCount = 0
-	addedCount = 0
-	if provider, ok := i.registry.GetProvider(config.Type); ok {
-
-		i.validator.InitRules(config)
-
-		items, _ := provider.GetItems(config)
-		for _, item := range items {
-			approved, added := i.processProviderItem(listName, &item)
-			if approved {
-				approvedCount++
-			}
-			if added {
-				addedCount++
+	stats := history.NewListStats(string(config.Type))
+	if listProvider, ok := i.registry.GetProvider(config.Type); !ok {
+		i.listFailed(listName, stats, fmt.Errorf("invalid list type '%s'", config.Type))
+	} else if err := i.validator.InitRules(config); err != nil {
+		i.listFailed(listName, stats, fmt.Errorf("invalid list rules: %w", err))
+	} else {
+		items, err := listProvider.GetItems(config)
+		if err != nil {
+			i.listFailed(listName, stats, err)
+		}
+		stats.Fetched = len(items)
+		metrics.ItemsFetched(listName, string(config.Type), len(items))
+		done := []provider.ListItem{}
+		for index := range items {
+			// the original item, before the resolution of its ids
+			item := items[index]
+			errorsCount := stats.Errors
+			i.processProviderItem(listName, config, &items[index], stats)
+			if stats.Errors == errorsCount {
+				done = append(done, item)
 			}
 		}
+		i.markDone(listName, listProvider, config, done, stats)
+		if err == nil && config.Sync.Action != "" {
+			i.syncList(listName, config, items, stats)
+		}
+	}
+	if stats.Errors == 0 {
+		metrics.ListSucceeded(listName)
 	}
-	i.logger.Info().Int("Approved", approvedCount).Int("Added", addedCount).Msgf("Finish list '%s'.", listName)
-	i.dispatcher.SendEventEndFeed(listName, approvedCount, addedCount)
-	return approvedCount, addedCount
+	i.logger.Info().Int("Approved", stats.Approved).Int("Added", stats.Added).Msgf("Finish list '%s'.", listName)
+	i.dispatcher.SendEventEndFeed(listName, stats.Approved, stats.Added)
+	return stats
 }
 
-func (i *Importer) ProcessList(listName string) {
+func (i *Importer) ProcessList(listName string) (*history.Run, error) {
 
+	run := history.NewRun()
 	configurations := i.getListsConfigurations()
 
-	if config, ok := configurations[strings.ToLower(listName)]; ok {
-		i.processProviderList(listName, config)
-	} else {
+	config, ok := configurations[strings.ToLower(listName)]
+	if !ok {
 		i.logger.Error().Msgf("Can't find the configuration for list '%s'", listName)
+		return run, fmt.Errorf("can't find the configuration for list '%s'", listName)
 	}
+
+	run.Lists[listName] = i.processProviderList(listName, config)
+	run.Finish()
+	i.saveState()
+
+	// the end of the run, for the digests
+	i.dispatcher.SendEventEndAllFeeds(run.Approved(), run.Added())
+	return run, nil
 }
 
-func (i *Importer) ProcessLists() {
+func (i *Importer) ProcessLists() *history.Run {
 
+	run := history.NewRun()
 	configurations := i.getListsConfigurations()
 
-	approvedCount := 0
-	addedCount := 0
 	for listName, config := range configurations {
-		approved, added := i.processProviderList(listName, config)
-		approvedCount += approved
-		addedCount += added
+		run.Lists[listName] = i.processProviderList(listName, config)
 	}
+	run.Finish()
+	i.saveState()
 
-	i.dispatcher.SendEventEndAllFeeds(approvedCount, addedCount)
-	i.logger.Info().Int("Approved", approvedCount).Int("Added", addedCount).Msg("Finish processing lists.")
+	i.dispatcher.SendEventEndAllFeeds(run.Approved(), run.Added())
+	i.logger.Info().Int("Approved", run.Approved()).Int("Added", run.Added()).Msg("Finish processing lists.")
+	return run
 }
@@ -0,0 +1,192 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package importer
+
+import (
+	"fmt"
+	"github.com/lightglitch/seekerr/importer/identity"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/services/guessit"
+	"github.com/lightglitch/seekerr/services/radarr"
+	"strconv"
+)
+
+// lookupMovie finds the movie in radarr by the tmdb or imdb id, the found
+// movies are kept for the next lookups.
+func (i *Importer) lookupMovie(item *provider.ListItem) (movieResult *radarr.Movie, err error) {
+	key := item.Imdb
+	if item.Tmdb != 0 {
+		key = fmt.Sprintf("tmdb:%d", item.Tmdb)
+	}
+	if movieResult, ok := i.lookups[key]; ok {
+		return movieResult, nil
+	}
+
+	if item.Tmdb != 0 {
+		movieResult, err = i.radarr.LookupMovieByTmdb(strconv.Itoa(item.Tmdb))
+	} else {
+		movieResult, err = i.radarr.LookupMovieByImdb(item.Imdb)
+	}
+	if err == nil && movieResult != nil {
+		i.lookups[key] = movieResult
+		if movieResult.TmdbID != 0 {
+			i.lookups[fmt.Sprintf("tmdb:%d", movieResult.TmdbID)] = movieResult
+		}
+		if movieResult.ImdbId != "" {
+			i.lookups[movieResult.ImdbId] = movieResult
+		}
+	}
+	return movieResult, err
+}
+
+// searchCandidates searches the title in omdb and radarr, the radarr movies
+// are kept for the next lookups.
+func (i *Importer) searchCandidates(title string, year int) ([]identity.Candidate, error) {
+	candidates := []identity.Candidate{}
+
+	params := map[string]string{}
+	if year != 0 {
+		params["y"] = strconv.Itoa(year)
+	}
+	search, omdbErr := i.omdb.SearchMovieByTitle(title, params)
+	if omdbErr == nil && search != nil {
+		for _, result := range search.Search {
+			// the years of series are ranges, like 2019–2021
+			resultYear := 0
+			if len(result.Year) >= 4 {
+				resultYear, _ = strconv.Atoi(result.Year[:4])
+			}
+			candidates = append(candidates, identity.Candidate{
+				Titles: []string{result.Title},
+				Year:   resultYear,
+				Imdb:   result.ImdbID,
+				Type:   result.Type,
+			})
+		}
+	}
+
+	movies, radarrErr := i.radarr.LookupMovie(title)
+	if radarrErr == nil && movies != nil {
+		for index := range *movies {
+			movie := &(*movies)[index]
+			titles := []string{movie.Title}
+			if movie.OriginalTitle != "" {
+				titles = append(titles, movie.OriginalTitle)
+			}
+			for _, alternate := range movie.AlternateTitles {
+				titles = append(titles, alternate.Title)
+			}
+			candidates = append(candidates, identity.Candidate{
+				Titles: titles,
+				Year:   movie.Year,
+				Imdb:   movie.ImdbId,
+				Tmdb:   movie.TmdbID,
+				Type:   "movie",
+			})
+			if movie.TmdbID != 0 {
+				i.lookups[fmt.Sprintf("tmdb:%d", movie.TmdbID)] = movie
+			}
+		}
+	}
+
+	if omdbErr != nil && radarrErr != nil {
+		return candidates, fmt.Errorf("searching movie '%s': %w", title, omdbErr)
+	}
+	return candidates, nil
+}
+
+// matchItem finds the movie of the item without ids by its title and year,
+// the raw release names are parsed when the year is unknown.
+func (i *Importer) matchItem(item *provider.ListItem) (identity.Match, error) {
+	title, year := item.Title, item.Year
+	if year == 0 {
+		if guessResult := guessit.Parse(item.Title); guessResult.Title != "" {
+			title, year = guessResult.Title, guessResult.Year
+		}
+	}
+
+	candidates, err := i.searchCandidates(title, year)
+	if err != nil {
+		return identity.Match{}, err
+	}
+	match, ok := i.matcher.Best(title, year, candidates)
+	if !ok {
+		return match, fmt.Errorf("movie '%s (%d)' not found", title, year)
+	}
+
+	i.logger.Debug().Float64("score", match.Score).Bool("confident", match.Confident).
+		Msgf("Matched '%s' to '%s (%d)'.", item.Title, match.Candidate.Titles[0], match.Candidate.Year)
+	return match, nil
+}
+
+// resolveItem fills the tmdb and imdb ids of the item before it's compared
+// with the radarr movies and exclusions. The items without ids are matched by
+// title and year, and the missing id is taken from the radarr lookup of the
+// other one. Returns false when the title match has low confidence.
+func (i *Importer) resolveItem(item *provider.ListItem) (confident bool, err error) {
+	confident = true
+	if item.Imdb == "" && item.Tmdb == 0 {
+		match, err := i.matchItem(item)
+		if err != nil {
+			return false, err
+		}
+		if !match.Confident {
+			i.logger.Warn().Float64("score", match.Score).Msgf("Low confidence match of '%s' to '%s (%d)'.",
+				item.Title, match.Candidate.Titles[0], match.Candidate.Year)
+		}
+		confident = match.Confident
+		item.Title = match.Candidate.Titles[0]
+		item.Year = match.Candidate.Year
+		item.Imdb = match.Candidate.Imdb
+		item.Tmdb = match.Candidate.Tmdb
+	}
+
+	if item.Imdb != "" && item.Tmdb != 0 {
+		return confident, nil
+	}
+	// the radarr movies have both ids
+	if i.existing.Contains(identity.NewKey(item.Tmdb, item.Imdb, "", 0)) {
+		return confident, nil
+	}
+
+	movieResult, err := i.lookupMovie(item)
+	if err != nil || movieResult == nil {
+		// without the other id the exclusions are matched by title and year
+		i.logger.Debug().Err(err).Msgf("Resolving movie '%s (%d)' in radarr.", item.Title, item.Year)
+		return confident, nil
+	}
+	if item.Tmdb == 0 {
+		item.Tmdb = movieResult.TmdbID
+	}
+	if item.Imdb == "" {
+		item.Imdb = movieResult.ImdbId
+	}
+	if item.Year == 0 {
+		item.Year = movieResult.Year
+	}
+	// the lists of ids only, like the file lists
+	if item.Title == "" {
+		item.Title = movieResult.Title
+	}
+	return confident, nil
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package state
+
+import (
+	"encoding/json"
+	"github.com/lightglitch/seekerr/services/radarr"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const DEFAULT_FILE = "var/state/seekerr.lists.json"
+
+// Entry is a movie added to radarr by a list.
+type Entry struct {
+	TmdbID       int        `json:"tmdbId"`
+	ImdbID       string     `json:"imdbId,omitempty"`
+	Title        string     `json:"title"`
+	Year         int        `json:"year"`
+	AddedAt      time.Time  `json:"addedAt"`
+	MissingSince *time.Time `json:"missingSince,omitempty"`
+}
+
+func NewStore(config *viper.Viper, logger *zerolog.Logger) *Store {
+	file := DEFAULT_FILE
+	if config != nil && config.GetString("file") != "" {
+		file = config.GetString("file")
+	}
+
+	return &Store{
+		logger: logger.With().Str("Component", "State").Logger(),
+		file:   file,
+		lists:  map[string]map[int]*Entry{},
+	}
+}
+
+// Store keeps the provenance of the movies added by each list, by tmdb id,
+// in a json file.
+type Store struct {
+	logger zerolog.Logger
+	file   string
+	lists  map[string]map[int]*Entry
+	mutex  sync.Mutex
+}
+
+func (s *Store) Load() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := os.ReadFile(s.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	lists := map[string]map[int]*Entry{}
+	if err := json.Unmarshal(data, &lists); err != nil {
+		return err
+	}
+	s.lists = lists
+	return nil
+}
+
+func (s *Store) Save() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.file), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s.lists, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// replace the file only when fully written
+	tmp := s.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.file)
+}
+
+// Track records the movie as added by the list.
+func (s *Store) Track(list string, movie *radarr.Movie) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.lists[list]; !ok {
+		s.lists[list] = map[int]*Entry{}
+	}
+	s.lists[list][movie.TmdbID] = &Entry{
+		TmdbID:  movie.TmdbID,
+		ImdbID:  movie.ImdbId,
+		Title:   movie.Title,
+		Year:    movie.Year,
+		AddedAt: time.Now(),
+	}
+}
+
+// Entries returns the movies added by the list.
+func (s *Store) Entries(list string) []*Entry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries := []*Entry{}
+	for _, entry := range s.lists[list] {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Forget stops tracking the movie of the list.
+func (s *Store) Forget(list string, tmdbId int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.lists[list], tmdbId)
+	if len(s.lists[list]) == 0 {
+		delete(s.lists, list)
+	}
+}
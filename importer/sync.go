@@ -0,0 +1,105 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package importer
+
+import (
+	"fmt"
+	"github.com/lightglitch/seekerr/importer/history"
+	"github.com/lightglitch/seekerr/importer/identity"
+	"github.com/lightglitch/seekerr/provider"
+	"time"
+)
+
+func (i *Importer) saveState() {
+	if i.state == nil {
+		return
+	}
+	if err := i.state.Save(); err != nil {
+		i.logger.Error().Err(err).Msg("Saving lists state")
+	}
+}
+
+// syncList unmonitors or deletes the movies added by the list that are no
+// longer in it, after the grace period and only when they don't have a file.
+func (i *Importer) syncList(listName string, config provider.ListConfig, items []provider.ListItem, stats *history.ListStats) {
+	if i.state == nil {
+		return
+	}
+	if config.Sync.Action != provider.SYNC_UNMONITOR && config.Sync.Action != provider.SYNC_DELETE {
+		i.logger.Error().Str("action", config.Sync.Action).Msgf("Invalid sync action of list '%s'.", listName)
+		return
+	}
+	// an empty list is more likely a broken provider than an empty ranking
+	if len(items) == 0 {
+		i.logger.Warn().Msgf("Skipping sync of empty list '%s'.", listName)
+		return
+	}
+
+	index := identity.NewIndex()
+	for _, item := range items {
+		index.Add(identity.NewKey(item.Tmdb, item.Imdb, item.Title, item.Year))
+	}
+
+	now := time.Now()
+	for _, entry := range i.state.Entries(listName) {
+		if index.Contains(identity.NewKey(entry.TmdbID, entry.ImdbID, entry.Title, entry.Year)) {
+			entry.MissingSince = nil
+			continue
+		}
+		if entry.MissingSince == nil {
+			i.logger.Info().Msgf("Movie '%s (%d)' left list '%s'.", entry.Title, entry.Year, listName)
+			entry.MissingSince = &now
+		}
+		if now.Sub(*entry.MissingSince) < config.Sync.GracePeriod {
+			continue
+		}
+
+		movie, ok := i.library[entry.TmdbID]
+		if !ok {
+			i.logger.Info().Msgf("Movie '%s (%d)' no longer in radarr.", entry.Title, entry.Year)
+			i.state.Forget(listName, entry.TmdbID)
+			continue
+		}
+		if movie.HasFile {
+			i.logger.Info().Msgf("Keeping movie '%s (%d)' with file.", entry.Title, entry.Year)
+			i.state.Forget(listName, entry.TmdbID)
+			continue
+		}
+
+		var err error
+		if config.Sync.Action == provider.SYNC_DELETE {
+			err = i.radarr.DeleteMovie(movie.ID, config.Sync.Exclude)
+		} else if err = i.radarr.SetMonitored(movie.ID, false); err == nil && config.Sync.Exclude {
+			err = i.radarr.AddExclusion(&movie)
+		}
+		if err != nil {
+			i.logger.Error().Err(err).Msgf("Removing movie '%s (%d)'.", entry.Title, entry.Year)
+			stats.Error(fmt.Errorf("removing movie '%s (%d)' from radarr: %w", entry.Title, entry.Year, err))
+			continue
+		}
+
+		i.logger.Info().Str("action", config.Sync.Action).Msgf("[REMOVED] Movie '%s (%d)' left list '%s'.", entry.Title, entry.Year, listName)
+		stats.RemovedMovie(fmt.Sprintf("%s (%d)", entry.Title, entry.Year))
+		i.state.Forget(listName, entry.TmdbID)
+	}
+}
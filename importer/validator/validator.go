@@ -43,13 +43,17 @@ func NewRuleValidatior(logger *zerolog.Logger) *RuleValidatior {
 }
 
 type RuleValidatior struct {
-	logger        zerolog.Logger
-	rules         []*vm.Program
-	revisionRules []*vm.Program
+	logger             zerolog.Logger
+	rules              []*vm.Program
+	ruleSources        []string
+	revisionRules      []*vm.Program
+	autoExcludeRules   []*vm.Program
+	autoExcludeSources []string
 }
 
 func (v *RuleValidatior) InitRules(config provider.ListConfig) error {
 	v.rules = []*vm.Program{}
+	v.ruleSources = []string{}
 	v.revisionRules = []*vm.Program{}
 	env := &RuleEnv{}
 
@@ -62,6 +66,7 @@ func (v *RuleValidatior) InitRules(config provider.ListConfig) error {
 		}
 
 		v.rules = append(v.rules, compiledRule)
+		v.ruleSources = append(v.ruleSources, rule)
 	}
 
 	v.logger.Debug().Int("rules", len(v.rules)).Msg("Initialized list rules")
@@ -79,6 +84,22 @@ func (v *RuleValidatior) InitRules(config provider.ListConfig) error {
 
 	v.logger.Debug().Int("revision rules", len(v.revisionRules)).Msg("Initialized list rules")
 
+	v.autoExcludeRules = []*vm.Program{}
+	v.autoExcludeSources = []string{}
+	v.logger.Debug().Interface("auto exclude rules", config.Filter.AutoExclude).Msg("Prepare list rules")
+	for _, rule := range config.Filter.AutoExclude {
+		compiledRule, err := expr.Compile(rule, expr.Env(env), expr.AsBool())
+		if err != nil {
+			v.logger.Error().Err(err).Msgf("Invalid auto exclude rule: %q", rule)
+			return err
+		}
+
+		v.autoExcludeRules = append(v.autoExcludeRules, compiledRule)
+		v.autoExcludeSources = append(v.autoExcludeSources, rule)
+	}
+
+	v.logger.Debug().Int("auto exclude rules", len(v.autoExcludeRules)).Msg("Initialized list rules")
+
 	return nil
 }
 
@@ -110,6 +131,13 @@ func (v *RuleValidatior) IsItemForRevision(item *provider.ListItem) bool {
 }
 
 func (v *RuleValidatior) IsItemApproved(item *provider.ListItem) bool {
+	approved, _ := v.ValidateItem(item)
+	return approved
+}
+
+// ValidateItem checks the item against the exclude rules and returns the
+// expression of the rule that rejected it, if any.
+func (v *RuleValidatior) ValidateItem(item *provider.ListItem) (approved bool, rule string) {
 
 	env := RuleEnv{
 		ListItem: *item,
@@ -118,20 +146,45 @@ func (v *RuleValidatior) IsItemApproved(item *provider.ListItem) bool {
 
 	v.logger.Debug().Int("rules", len(v.rules)).Msg("Validating item rules")
 
-	for _, rule := range v.rules {
-		result, err := expr.Run(rule, env)
+	for index, program := range v.rules {
+		result, err := expr.Run(program, env)
 		if err != nil {
 			v.logger.Error().Err(err).Interface("item", item).Msg("Failed validation rule for item")
-			return false
+			return false, v.ruleSources[index]
 		}
 
 		expResult, ok := result.(bool)
 		if !ok || expResult {
-			return false
+			return false, v.ruleSources[index]
 		}
 	}
 
 	v.logger.Debug().Msg("Item approved")
 
-	return true
+	return true, ""
+}
+
+// IsItemAutoExcluded checks the item against the auto exclude rules and
+// returns the expression of the rule that matched it, if any. The items that
+// fail a rule are not excluded.
+func (v *RuleValidatior) IsItemAutoExcluded(item *provider.ListItem) (excluded bool, rule string) {
+
+	env := RuleEnv{
+		ListItem: *item,
+		Now:      func() time.Time { return time.Now().UTC() },
+	}
+
+	for index, program := range v.autoExcludeRules {
+		result, err := expr.Run(program, env)
+		if err != nil {
+			v.logger.Error().Err(err).Interface("item", item).Msg("Failed auto exclude rule for item")
+			continue
+		}
+
+		if expResult, ok := result.(bool); ok && expResult {
+			return true, v.autoExcludeSources[index]
+		}
+	}
+
+	return false, ""
 }
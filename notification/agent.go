@@ -23,20 +23,32 @@
 package notification
 
 import (
+	"fmt"
 	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/utils/http"
 	"github.com/rs/zerolog"
 )
 
+// Agent sends the events to one service, the dispatcher retries the events
+// when SendEvent returns an error that is not permanent.
 type Agent interface {
-	SendEvent(event Event)
+	SendEvent(event Event) error
 	Name() string
 }
 
+// Flusher is implemented by the agents that buffer the events, the buffer is
+// sent when the dispatcher is closed.
+type Flusher interface {
+	Flush() error
+}
+
 func NewWebhookAgent(url string, events []EventType, logger zerolog.Logger, restyClient *resty.Client) *WebhookAgent {
 	return &WebhookAgent{
 		Logger:      logger,
 		restyClient: restyClient,
 		Url:         url,
+		Method:      resty.MethodPost,
+		Headers:     map[string]string{},
 		events:      events,
 	}
 }
@@ -45,19 +57,24 @@ type WebhookAgent struct {
 	Logger      zerolog.Logger
 	restyClient *resty.Client
 	Url         string
+	Method      string
+	Headers     map[string]string
 	events      []EventType
 }
 
 func (a *WebhookAgent) initRequest() *resty.Request {
-	return a.restyClient.R().
+	return http.ServiceRequest(a.restyClient, "notification").
 		SetHeaders(map[string]string{
 			"Content-Type": "application/json",
-		})
+		}).
+		SetHeaders(a.Headers)
 }
 
 func (a *WebhookAgent) IsSubscribe(eventType EventType) bool {
 
-	if len(a.events) == 0 {
+	// digests are only built for the agents in digest mode and with the
+	// subscribed events
+	if len(a.events) == 0 || eventType == DIGEST {
 		return true
 	}
 
@@ -69,18 +86,32 @@ func (a *WebhookAgent) IsSubscribe(eventType EventType) bool {
 	return false
 }
 
-func (a *WebhookAgent) SendMessage(event Event, message interface{}) {
+func (a *WebhookAgent) SendMessage(event Event, message interface{}) error {
+	return a.SendMessageTo(a.Url, event, message)
+}
+
+// SendMessageTo sends the message to other url than the configured one, for
+// services that use one endpoint for each type of message.
+func (a *WebhookAgent) SendMessageTo(url string, event Event, message interface{}) error {
 	a.Logger.Debug().Interface("event", event).Interface("message", message).Msg("Sending event")
-	if message != nil {
-		resp, err := a.
-			initRequest().
-			SetBody(message).
-			Post(a.Url)
-		if err != nil {
-			a.Logger.Error().Err(err).Msgf("Error sending event")
-		}
-		if resp.IsError() {
-			a.Logger.Error().Str("response", string(resp.Body())).Msgf("Error sending event")
+	if message == nil {
+		return nil
+	}
+
+	resp, err := a.
+		initRequest().
+		SetBody(message).
+		Execute(a.Method, url)
+	if err != nil {
+		return fmt.Errorf("sending event: %w", err)
+	}
+	if resp.IsError() {
+		err = fmt.Errorf("sending event: %s - %s", resp.Status(), resp.String())
+		// the same request will fail again, except when throttled
+		if resp.StatusCode() < 500 && resp.StatusCode() != 429 {
+			return Permanent(err)
 		}
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,169 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package apprise
+
+import (
+	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/notification"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"net/url"
+	"strings"
+)
+
+const (
+	TYPE_INFO    = "info"
+	TYPE_SUCCESS = "success"
+	TYPE_WARNING = "warning"
+	TYPE_FAILURE = "failure"
+)
+
+// AppriseMessage is the body of the notify endpoints of the Apprise API, see
+// https://github.com/caronc/apprise-api
+type AppriseMessage struct {
+	Urls   []string `json:"urls,omitempty"`
+	Tag    string   `json:"tag,omitempty"`
+	Title  string   `json:"title,omitempty"`
+	Body   string   `json:"body"`
+	Type   string   `json:"type"`
+	Format string   `json:"format"`
+	Attach []string `json:"attach,omitempty"`
+}
+
+func NewAppriseAgent(config *viper.Viper, logger *zerolog.Logger, restyClient *resty.Client) *AppriseAgent {
+
+	agentLogger := logger.With().Str("Component", "AppriseAgent").Logger()
+
+	apiUrl, err := url.Parse(config.GetString("url"))
+	if err != nil || apiUrl.Host == "" {
+		agentLogger.Error().Err(err).Str("url", config.GetString("url")).Msg("Invalid apprise url configuration.")
+		return nil
+	}
+
+	// with a key the notification services are the ones saved in the api,
+	// otherwise they are sent in the request
+	key := config.GetString("key")
+	urls := config.GetStringSlice("urls")
+	if key == "" && len(urls) == 0 {
+		agentLogger.Error().Msg("Missing apprise key or urls configuration.")
+		return nil
+	}
+	notifyUrl := strings.TrimSuffix(apiUrl.String(), "/") + "/notify"
+	if key != "" {
+		notifyUrl += "/" + url.PathEscape(key)
+		urls = nil
+	}
+
+	titles, err := notification.NewMessageTemplates(config, "titles", notification.DefaultTitles)
+	if err != nil {
+		agentLogger.Error().Err(err).Msg("Invalid apprise configuration.")
+		return nil
+	}
+	messages, err := notification.NewMessageTemplates(config, "templates", notification.DefaultMessages)
+	if err != nil {
+		agentLogger.Error().Err(err).Msg("Invalid apprise configuration.")
+		return nil
+	}
+
+	events := []notification.EventType{}
+	_ = config.UnmarshalKey("events", &events)
+
+	agent := &AppriseAgent{
+		WebhookAgent: *notification.NewWebhookAgent(notifyUrl, events, agentLogger, restyClient),
+		urls:         urls,
+		tag:          config.GetString("tag"),
+		attach:       true,
+		titles:       titles,
+		messages:     messages,
+	}
+	if config.IsSet("attachPoster") {
+		agent.attach = config.GetBool("attachPoster")
+	}
+
+	return agent
+}
+
+// AppriseAgent sends the events to an Apprise API server, that fans out to
+// any of the services supported by Apprise.
+type AppriseAgent struct {
+	notification.WebhookAgent
+	urls     []string
+	tag      string
+	attach   bool
+	titles   *notification.MessageTemplates
+	messages *notification.MessageTemplates
+}
+
+func (a *AppriseAgent) Name() string {
+	return "apprise"
+}
+
+func (a *AppriseAgent) getMessage(event notification.Event) interface{} {
+	a.WebhookAgent.Logger.Debug().Interface("event", event).Msg("Processing message")
+
+	title, err := a.titles.Render(event)
+	if err != nil {
+		a.WebhookAgent.Logger.Warn().Err(err).Interface("event", event).Msg("Rendering title template")
+	}
+	text, err := a.messages.Render(event)
+	if err != nil {
+		a.WebhookAgent.Logger.Warn().Err(err).Interface("event", event).Msg("Rendering message template")
+	}
+	if text == "" {
+		a.WebhookAgent.Logger.Error().Interface("event", event).Msg("Invalid event type")
+		return nil
+	}
+
+	message := AppriseMessage{
+		Urls:   a.urls,
+		Tag:    a.tag,
+		Title:  title,
+		Body:   text,
+		Type:   TYPE_INFO,
+		Format: "text",
+	}
+
+	switch event.Type {
+	case notification.ADDED_MOVIE:
+		message.Type = TYPE_SUCCESS
+	case notification.REVISION_MOVIE:
+		message.Type = TYPE_WARNING
+	case notification.LIST_FAILED, notification.ITEM_ERROR:
+		message.Type = TYPE_FAILURE
+	}
+
+	if event.Type == notification.ADDED_MOVIE || event.Type == notification.REVISION_MOVIE {
+		if poster := notification.NewTemplateData(event).Poster; a.attach && poster != "" {
+			message.Attach = []string{poster}
+		}
+	}
+
+	return message
+}
+
+func (a *AppriseAgent) SendEvent(event notification.Event) error {
+	if a.IsSubscribe(event.Type) {
+		return a.SendMessage(event, a.getMessage(event))
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/services/radarr"
+	"github.com/spf13/viper"
+	"time"
+)
+
+const (
+	MODE_IMMEDIATE = "immediate"
+	MODE_DIGEST    = "digest"
+)
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error that won't be fixed by retrying the event, like
+// an invalid webhook url, so it goes directly to the dead letter log.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// AgentOptions control when the dispatcher delivers the events to one agent.
+type AgentOptions struct {
+	Mode       string
+	DigestOn   EventType
+	QuietHours *QuietHours
+}
+
+// NewAgentOptions reads the delivery options from the agent configuration,
+// the config may be nil for the defaults.
+func NewAgentOptions(config *viper.Viper) (AgentOptions, error) {
+	options := AgentOptions{
+		Mode:     MODE_IMMEDIATE,
+		DigestOn: FINISH_ALL_FEEDS,
+	}
+	if config == nil {
+		return options, nil
+	}
+
+	if config.IsSet("mode") {
+		options.Mode = config.GetString("mode")
+	}
+	if options.Mode != MODE_IMMEDIATE && options.Mode != MODE_DIGEST {
+		return options, fmt.Errorf("invalid notification mode %q", options.Mode)
+	}
+
+	if config.IsSet("digestOn") {
+		options.DigestOn = EventType(config.GetString("digestOn"))
+	}
+	if options.DigestOn != FINISH_FEED && options.DigestOn != FINISH_ALL_FEEDS {
+		return options, fmt.Errorf("invalid digest event %q", options.DigestOn)
+	}
+
+	if config.IsSet("quietHours") {
+		quietHours, err := NewQuietHours(config.GetString("quietHours.start"), config.GetString("quietHours.end"))
+		if err != nil {
+			return options, err
+		}
+		options.QuietHours = quietHours
+	}
+
+	return options, nil
+}
+
+// QuietHours is a daily window, in local time, where the messages are held.
+// The window can cross midnight, like 22:00 to 07:00.
+type QuietHours struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+func parseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quiet hours time %q, expected HH:MM", value)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+func NewQuietHours(start string, end string) (*QuietHours, error) {
+	startClock, err := parseClock(start)
+	if err != nil {
+		return nil, err
+	}
+	endClock, err := parseClock(end)
+	if err != nil {
+		return nil, err
+	}
+	if startClock == endClock {
+		return nil, fmt.Errorf("quiet hours start and end can't be the same")
+	}
+	return &QuietHours{Start: startClock, End: endClock}, nil
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func (q *QuietHours) Contains(t time.Time) bool {
+	clock := t.Sub(midnight(t))
+	if q.Start < q.End {
+		return clock >= q.Start && clock < q.End
+	}
+	return clock >= q.Start || clock < q.End
+}
+
+// Until returns the time left until the window ends.
+func (q *QuietHours) Until(t time.Time) time.Duration {
+	end := midnight(t).Add(q.End)
+	if !end.After(t) {
+		end = midnight(t.AddDate(0, 0, 1)).Add(q.End)
+	}
+	return end.Sub(t)
+}
+
+// Subscriber is implemented by the agents that only want some event types,
+// digests are built only with the events the agent subscribed.
+type Subscriber interface {
+	IsSubscribe(eventType EventType) bool
+}
+
+func isSubscribed(agent Agent, eventType EventType) bool {
+	if subscriber, ok := agent.(Subscriber); ok {
+		return subscriber.IsSubscribe(eventType)
+	}
+	return true
+}
+
+// DigestTitle summarizes the digest event in one line.
+func DigestTitle(event Event) string {
+	events, _ := event.Data["events"].([]Event)
+	added := 0
+	for _, e := range events {
+		if e.Type == ADDED_MOVIE {
+			added++
+		}
+	}
+	if name, ok := event.Data["name"]; ok {
+		return fmt.Sprintf("Seekerr: %s, added %d movies", name, added)
+	}
+	return fmt.Sprintf("Seekerr: added %d movies", added)
+}
+
+func movieTitle(event Event) string {
+	if movie, ok := event.Data["movie"].(*radarr.Movie); ok && movie != nil {
+		return fmt.Sprintf("%s (%d)", movie.Title, movie.Year)
+	}
+	if item, ok := event.Data["item"].(*provider.ListItem); ok && item != nil {
+		return fmt.Sprintf("%s (%d)", item.Title, item.Year)
+	}
+	return ""
+}
+
+// DigestLines describes each event of the digest in one line of plain text.
+func DigestLines(event Event) []string {
+	events, _ := event.Data["events"].([]Event)
+	lines := []string{}
+	for _, e := range events {
+		switch e.Type {
+		case ADDED_MOVIE:
+			lines = append(lines, fmt.Sprintf("Added '%s' from feed %s", movieTitle(e), e.Data["name"]))
+		case REVISION_MOVIE:
+			lines = append(lines, fmt.Sprintf("For revision '%s' from feed %s", movieTitle(e), e.Data["name"]))
+		case LIST_FAILED:
+			lines = append(lines, fmt.Sprintf("Failed processing feed %s: %s", e.Data["name"], e.Data["error"]))
+		case ITEM_ERROR:
+			lines = append(lines, fmt.Sprintf("Error processing movie '%s' in feed %s: %s", movieTitle(e), e.Data["name"], e.Data["error"]))
+		}
+	}
+	return lines
+}
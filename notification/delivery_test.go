@@ -0,0 +1,95 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package notification
+
+import (
+	"testing"
+	"time"
+)
+
+func clock(hour int, minute int) time.Time {
+	return time.Date(2023, time.March, 1, hour, minute, 0, 0, time.UTC)
+}
+
+func TestQuietHoursContains(t *testing.T) {
+	tests := []struct {
+		start    string
+		end      string
+		time     time.Time
+		expected bool
+	}{
+		{start: "22:00", end: "07:00", time: clock(21, 59), expected: false},
+		{start: "22:00", end: "07:00", time: clock(22, 0), expected: true},
+		{start: "22:00", end: "07:00", time: clock(23, 30), expected: true},
+		{start: "22:00", end: "07:00", time: clock(0, 0), expected: true},
+		{start: "22:00", end: "07:00", time: clock(6, 59), expected: true},
+		{start: "22:00", end: "07:00", time: clock(7, 0), expected: false},
+		{start: "22:00", end: "07:00", time: clock(12, 0), expected: false},
+		{start: "09:00", end: "17:00", time: clock(8, 59), expected: false},
+		{start: "09:00", end: "17:00", time: clock(9, 0), expected: true},
+		{start: "09:00", end: "17:00", time: clock(16, 59), expected: true},
+		{start: "09:00", end: "17:00", time: clock(17, 0), expected: false},
+		{start: "09:00", end: "17:00", time: clock(23, 0), expected: false},
+	}
+
+	for _, test := range tests {
+		quietHours, err := NewQuietHours(test.start, test.end)
+		if err != nil {
+			t.Fatalf("NewQuietHours(%s, %s): %v", test.start, test.end, err)
+		}
+		if result := quietHours.Contains(test.time); result != test.expected {
+			t.Errorf("%s-%s Contains(%s) = %t, expected %t", test.start, test.end, test.time.Format("15:04"), result, test.expected)
+		}
+	}
+}
+
+func TestQuietHoursUntil(t *testing.T) {
+	tests := []struct {
+		start    string
+		end      string
+		time     time.Time
+		expected time.Duration
+	}{
+		{start: "22:00", end: "07:00", time: clock(22, 0), expected: 9 * time.Hour},
+		{start: "22:00", end: "07:00", time: clock(23, 30), expected: 7*time.Hour + 30*time.Minute},
+		{start: "22:00", end: "07:00", time: clock(0, 0), expected: 7 * time.Hour},
+		{start: "22:00", end: "07:00", time: clock(6, 59), expected: time.Minute},
+		{start: "09:00", end: "17:00", time: clock(9, 0), expected: 8 * time.Hour},
+		{start: "09:00", end: "17:00", time: clock(16, 30), expected: 30 * time.Minute},
+	}
+
+	for _, test := range tests {
+		quietHours, _ := NewQuietHours(test.start, test.end)
+		if result := quietHours.Until(test.time); result != test.expected {
+			t.Errorf("%s-%s Until(%s) = %s, expected %s", test.start, test.end, test.time.Format("15:04"), result, test.expected)
+		}
+	}
+}
+
+func TestNewQuietHoursError(t *testing.T) {
+	for _, window := range [][2]string{{"22:00", "22:00"}, {"25:00", "07:00"}, {"22:00", "7am"}} {
+		if _, err := NewQuietHours(window[0], window[1]); err == nil {
+			t.Errorf("NewQuietHours(%s, %s) expected error", window[0], window[1])
+		}
+	}
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package discord
+
+import (
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/notification"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/services/radarr"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	COLOR_ADDED    = 0x2ecc71
+	COLOR_REVISION = 0xf1c40f
+	COLOR_ERROR    = 0xe74c3c
+
+	DESCRIPTION_LIMIT = 4096
+)
+
+type DiscordMessage struct {
+	Username string         `json:"username,omitempty"`
+	Content  string         `json:"content,omitempty"`
+	Embeds   []DiscordEmbed `json:"embeds,omitempty"`
+}
+
+type DiscordEmbed struct {
+	Title       string         `json:"title,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Thumbnail   *DiscordImage  `json:"thumbnail,omitempty"`
+	Fields      []DiscordField `json:"fields,omitempty"`
+	Footer      *DiscordFooter `json:"footer,omitempty"`
+}
+
+type DiscordImage struct {
+	URL string `json:"url"`
+}
+
+type DiscordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type DiscordFooter struct {
+	Text string `json:"text"`
+}
+
+func NewDiscordAgent(config *viper.Viper, logger *zerolog.Logger, restyClient *resty.Client) *DiscordAgent {
+
+	url, err := url.Parse(config.GetString("webhook"))
+	if err != nil {
+		logger.Err(err)
+		return nil
+	}
+
+	events := []notification.EventType{}
+	_ = config.UnmarshalKey("events", &events)
+
+	return &DiscordAgent{
+		WebhookAgent: *notification.NewWebhookAgent(url.String(), events, logger.With().Str("Component", "DiscordAgent").Logger(), restyClient),
+		username:     config.GetString("username"),
+	}
+}
+
+type DiscordAgent struct {
+	notification.WebhookAgent
+	username string
+}
+
+func (g *DiscordAgent) Name() string {
+	return "discord"
+}
+
+func (g *DiscordAgent) getMovieEmbed(title string, color int, movie *radarr.Movie, item *provider.ListItem) DiscordEmbed {
+	tmdbUrl := fmt.Sprintf("https://www.themoviedb.org/movie/%d", movie.TmdbID)
+	embed := DiscordEmbed{
+		Title:       fmt.Sprintf("%s (%d)", movie.Title, movie.Year),
+		URL:         tmdbUrl,
+		Description: movie.Overview,
+		Color:       color,
+		Footer:      &DiscordFooter{Text: title},
+	}
+	if item != nil {
+		embed.Fields = []DiscordField{
+			{Name: "IMDB", Value: fmt.Sprintf("%.1f/10", item.Ratings.Imdb), Inline: true},
+			{Name: "Metacritic", Value: fmt.Sprintf("%d/100", item.Ratings.Metacritic), Inline: true},
+			{Name: "Rotten Tomatoes", Value: fmt.Sprintf("%d%%", item.Ratings.RottenTomatoes), Inline: true},
+		}
+	}
+
+	links := fmt.Sprintf("[TMDb](%s)", tmdbUrl)
+	if movie.ImdbId != "" {
+		embed.URL = fmt.Sprintf("https://www.imdb.com/title/%s", movie.ImdbId)
+		links = fmt.Sprintf("[IMDb](%s) | %s", embed.URL, links)
+	}
+	embed.Fields = append(embed.Fields, DiscordField{Name: "Links", Value: links})
+
+	if poster := movie.GetPoster(); poster != "" {
+		embed.Thumbnail = &DiscordImage{URL: poster}
+	}
+
+	return embed
+}
+
+// joinLines joins the lines that fit in the limit, noting how many were left out.
+func joinLines(lines []string, limit int) string {
+	text := ""
+	for i, line := range lines {
+		more := fmt.Sprintf("\n… and %d more", len(lines)-i)
+		if utf8.RuneCountInString(text+line+more) > limit {
+			return strings.TrimSpace(text + more)
+		}
+		text += line + "\n"
+	}
+	return strings.TrimSpace(text)
+}
+
+func (g *DiscordAgent) getMessage(event notification.Event) interface{} {
+	g.WebhookAgent.Logger.Debug().Interface("event", event).Msg("Processing message")
+	message := DiscordMessage{Username: g.username}
+
+	switch event.Type {
+	case notification.START_FEED:
+		message.Content = fmt.Sprintf("Start processing feed **%s**", event.Data["name"])
+	case notification.FINISH_FEED:
+		message.Content = fmt.Sprintf("Finish processing feed **%s**, added %d movies", event.Data["name"], event.Data["added"])
+	case notification.FINISH_ALL_FEEDS:
+		message.Content = fmt.Sprintf("Finish processing all feeds, added %d movies", event.Data["added"])
+	case notification.ADDED_MOVIE, notification.REVISION_MOVIE:
+		// the events read from the spool may miss the movie or the item
+		movie, ok := event.Data["movie"].(*radarr.Movie)
+		if !ok || movie == nil {
+			g.WebhookAgent.Logger.Warn().Interface("event", event).Msg("Skipping event without movie")
+			return nil
+		}
+		item, _ := event.Data["item"].(*provider.ListItem)
+		if event.Type == notification.ADDED_MOVIE {
+			message.Embeds = append(message.Embeds, g.getMovieEmbed(
+				fmt.Sprintf("Added from feed %s", event.Data["name"]), COLOR_ADDED, movie, item))
+		} else {
+			message.Embeds = append(message.Embeds, g.getMovieEmbed(
+				fmt.Sprintf("For revision from feed %s", event.Data["name"]), COLOR_REVISION, movie, item))
+		}
+	case notification.LIST_FAILED:
+		message.Embeds = append(message.Embeds, DiscordEmbed{
+			Title:       fmt.Sprintf("Failed processing feed %s", event.Data["name"]),
+			Description: fmt.Sprintf("%s", event.Data["error"]),
+			Color:       COLOR_ERROR,
+		})
+	case notification.ITEM_ERROR:
+		title := ""
+		if item, ok := event.Data["item"].(*provider.ListItem); ok && item != nil {
+			title = fmt.Sprintf(" '%s (%d)'", item.Title, item.Year)
+		}
+		message.Content = fmt.Sprintf("Error processing movie%s in feed **%s**: %s", title, event.Data["name"], event.Data["error"])
+	case notification.DIGEST:
+		message.Embeds = append(message.Embeds, DiscordEmbed{
+			Title:       notification.DigestTitle(event),
+			Description: joinLines(notification.DigestLines(event), DESCRIPTION_LIMIT),
+			Color:       COLOR_ADDED,
+		})
+	default:
+		g.WebhookAgent.Logger.Error().Interface("event", event).Msg("Invalid event type")
+		return nil
+	}
+
+	return message
+}
+
+func (a *DiscordAgent) SendEvent(event notification.Event) error {
+	if a.IsSubscribe(event.Type) {
+		return a.SendMessage(event, a.getMessage(event))
+	}
+	return nil
+}
@@ -0,0 +1,424 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package email
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"github.com/lightglitch/seekerr/notification"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/services/radarr"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	DEFAULT_PORT    = 587
+	DEFAULT_SUBJECT = "Seekerr: new movies"
+)
+
+var htmlDigest = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Helvetica, Arial, sans-serif; color: #333333;">
+{{- range .Lists }}
+<h2>{{ .Name }}</h2>
+<table cellpadding="8" cellspacing="0" border="0">
+{{- range .Movies }}
+<tr>
+<td valign="top">{{ if .Poster }}<img src="{{ .Poster }}" alt="{{ .Title }}" width="92">{{ end }}</td>
+<td valign="top">
+<a href="{{ .Url }}"><strong>{{ .Title }} ({{ .Year }})</strong></a>{{ if .Revision }} <em>for revision</em>{{ end }}<br>
+IMDB <strong>{{ printf "%.1f" .Ratings.Imdb }}</strong>/10 | Metacritic <strong>{{ .Ratings.Metacritic }}</strong>/100 | Rotten Tomatoes <strong>{{ .Ratings.RottenTomatoes }}%</strong><br>
+<p>{{ .Overview }}</p>
+</td>
+</tr>
+{{- end }}
+</table>
+{{- end }}
+</body>
+</html>
+`))
+
+var textDigest = template.Must(template.New("text").Parse(`
+{{- range .Lists -}}
+{{ .Name }}
+{{ range .Movies }}
+- {{ .Title }} ({{ .Year }}){{ if .Revision }} [for revision]{{ end }}
+  IMDB {{ printf "%.1f" .Ratings.Imdb }}/10 | Metacritic {{ .Ratings.Metacritic }}/100 | Rotten Tomatoes {{ .Ratings.RottenTomatoes }}%
+  {{ .Url }}
+{{ end }}
+{{ end -}}
+`))
+
+type digestMovie struct {
+	Title    string
+	Year     int
+	Overview string
+	Url      string
+	Poster   string
+	Revision bool
+	Ratings  provider.Ratings
+}
+
+type digestList struct {
+	Name   string
+	Movies []digestMovie
+}
+
+type digest struct {
+	Lists []digestList
+}
+
+func NewEmailAgent(config *viper.Viper, logger *zerolog.Logger) *EmailAgent {
+
+	if config.GetString("host") == "" {
+		logger.Error().Msg("Missing email host configuration.")
+		return nil
+	}
+
+	if config.GetString("from") == "" {
+		logger.Error().Msg("Missing email from configuration.")
+		return nil
+	}
+
+	to := config.GetStringSlice("to")
+	if len(to) == 0 {
+		logger.Error().Msg("Missing email to configuration.")
+		return nil
+	}
+
+	port := DEFAULT_PORT
+	if config.IsSet("port") {
+		port = config.GetInt("port")
+	}
+
+	subject := DEFAULT_SUBJECT
+	if config.GetString("subject") != "" {
+		subject = config.GetString("subject")
+	}
+
+	events := []notification.EventType{}
+	_ = config.UnmarshalKey("events", &events)
+
+	return &EmailAgent{
+		logger:   logger.With().Str("Component", "EmailAgent").Logger(),
+		host:     config.GetString("host"),
+		port:     port,
+		username: config.GetString("username"),
+		password: config.GetString("password"),
+		tls:      config.GetBool("tls"),
+		from:     config.GetString("from"),
+		to:       to,
+		subject:  subject,
+		events:   events,
+		lists:    map[string][]digestMovie{},
+	}
+}
+
+// EmailAgent buffers the movies during the import and sends them in one email
+// when all the feeds are processed.
+type EmailAgent struct {
+	logger   zerolog.Logger
+	host     string
+	port     int
+	username string
+	password string
+	tls      bool
+	from     string
+	to       []string
+	subject  string
+	events   []notification.EventType
+	mutex    sync.Mutex
+	lists    map[string][]digestMovie
+}
+
+func (a *EmailAgent) Name() string {
+	return "email"
+}
+
+func (a *EmailAgent) IsSubscribe(eventType notification.EventType) bool {
+	if len(a.events) == 0 || eventType == notification.DIGEST {
+		return true
+	}
+
+	for _, et := range a.events {
+		if et == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// newDigestMovie returns the list name and the movie of the event, false
+// when the event has no movie, like the events restored without it.
+func newDigestMovie(event notification.Event) (string, digestMovie, bool) {
+	movie, ok := event.Data["movie"].(*radarr.Movie)
+	if !ok || movie == nil {
+		return "", digestMovie{}, false
+	}
+	ratings := provider.Ratings{}
+	if item, ok := event.Data["item"].(*provider.ListItem); ok && item != nil {
+		ratings = item.Ratings
+	}
+	name := fmt.Sprintf("%s", event.Data["name"])
+
+	url := fmt.Sprintf("https://www.themoviedb.org/movie/%d", movie.TmdbID)
+	if movie.ImdbId != "" {
+		url = fmt.Sprintf("https://www.imdb.com/title/%s", movie.ImdbId)
+	}
+
+	return name, digestMovie{
+		Title:    movie.Title,
+		Year:     movie.Year,
+		Overview: movie.Overview,
+		Url:      url,
+		Poster:   movie.GetPoster(),
+		Revision: event.Type == notification.REVISION_MOVIE,
+		Ratings:  ratings,
+	}, true
+}
+
+// newDigest groups the movies by list, nil when there are no movies.
+func newDigest(lists map[string][]digestMovie) *digest {
+	if len(lists) == 0 {
+		return nil
+	}
+
+	result := &digest{}
+	for name, movies := range lists {
+		result.Lists = append(result.Lists, digestList{Name: name, Movies: movies})
+	}
+	sort.Slice(result.Lists, func(i, j int) bool {
+		return result.Lists[i].Name < result.Lists[j].Name
+	})
+	return result
+}
+
+func (a *EmailAgent) bufferMovie(event notification.Event) {
+	name, movie, ok := newDigestMovie(event)
+	if !ok {
+		a.logger.Warn().Interface("event", event).Msg("Skipping event without movie")
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.lists[name] = append(a.lists[name], movie)
+}
+
+// takeDigest returns the buffered movies grouped by list and empties the buffer.
+func (a *EmailAgent) takeDigest() *digest {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	result := newDigest(a.lists)
+	a.lists = map[string][]digestMovie{}
+	return result
+}
+
+// restoreDigest puts back the movies of a digest that wasn't sent, so they
+// are sent by the retry.
+func (a *EmailAgent) restoreDigest(content *digest) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, list := range content.Lists {
+		a.lists[list.Name] = append(list.Movies, a.lists[list.Name]...)
+	}
+}
+
+func writePart(writer *multipart.Writer, contentType string, body []byte) error {
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := quotedprintable.NewWriter(part)
+	if _, err = encoder.Write(body); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func (a *EmailAgent) buildMessage(content *digest) ([]byte, error) {
+	text := bytes.Buffer{}
+	if err := textDigest.Execute(&text, content); err != nil {
+		return nil, err
+	}
+
+	html := bytes.Buffer{}
+	if err := htmlDigest.Execute(&html, content); err != nil {
+		return nil, err
+	}
+
+	body := bytes.Buffer{}
+	writer := multipart.NewWriter(&body)
+	if err := writePart(writer, "text/plain", text.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writePart(writer, "text/html", html.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	message := bytes.Buffer{}
+	fmt.Fprintf(&message, "From: %s\r\n", a.from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(a.to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", a.subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", writer.Boundary())
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+func (a *EmailAgent) send(message []byte) error {
+	address := net.JoinHostPort(a.host, strconv.Itoa(a.port))
+
+	var auth smtp.Auth
+	if a.username != "" {
+		auth = smtp.PlainAuth("", a.username, a.password, a.host)
+	}
+
+	if !a.tls {
+		// uses STARTTLS when the server supports it
+		return smtp.SendMail(address, auth, a.from, a.to, message)
+	}
+
+	conn, err := tls.Dial("tcp", address, &tls.Config{ServerName: a.host})
+	if err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, a.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(a.from); err != nil {
+		return err
+	}
+	for _, to := range a.to {
+		if err = client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(message); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (a *EmailAgent) sendDigest(content *digest) error {
+	if content == nil {
+		a.logger.Debug().Msg("No movies to send")
+		return nil
+	}
+
+	message, err := a.buildMessage(content)
+	if err != nil {
+		return notification.Permanent(err)
+	}
+
+	if err = a.send(message); err != nil {
+		return fmt.Errorf("sending email: %w", err)
+	}
+
+	a.logger.Info().Int("lists", len(content.Lists)).Msg("Sent email digest")
+	return nil
+}
+
+func (a *EmailAgent) SendEvent(event notification.Event) error {
+	switch event.Type {
+	case notification.ADDED_MOVIE, notification.REVISION_MOVIE:
+		if a.IsSubscribe(event.Type) {
+			a.bufferMovie(event)
+		}
+	case notification.DIGEST:
+		// the dispatcher digest only has the subscribed events
+		lists := map[string][]digestMovie{}
+		events, _ := event.Data["events"].([]notification.Event)
+		for _, e := range events {
+			if e.Type == notification.ADDED_MOVIE || e.Type == notification.REVISION_MOVIE {
+				name, movie, ok := newDigestMovie(e)
+				if !ok {
+					a.logger.Warn().Interface("event", e).Msg("Skipping event without movie")
+					continue
+				}
+				lists[name] = append(lists[name], movie)
+			}
+		}
+		return a.sendDigest(newDigest(lists))
+	case notification.FINISH_ALL_FEEDS:
+		return a.Flush()
+	}
+	return nil
+}
+
+// Flush sends the buffered movies, they are kept for the retry when the
+// email can't be sent.
+func (a *EmailAgent) Flush() error {
+	content := a.takeDigest()
+	if err := a.sendDigest(content); err != nil {
+		if content != nil {
+			a.restoreDigest(content)
+		}
+		return err
+	}
+	return nil
+}
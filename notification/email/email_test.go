@@ -0,0 +1,223 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package email
+
+import (
+	"github.com/lightglitch/seekerr/notification"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/services/radarr"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// smtpServer is a local stand-in of a smtp server, it keeps the messages
+// and rejects the recipients while failing.
+type smtpServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	messages []string
+	failing  bool
+}
+
+func newSmtpServer(t *testing.T) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	server := &smtpServer{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	text := textproto.NewConn(conn)
+	defer text.Close()
+
+	_ = text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			_ = text.PrintfLine("250 localhost")
+		case "RCPT":
+			s.mutex.Lock()
+			failing := s.failing
+			s.mutex.Unlock()
+			if failing {
+				_ = text.PrintfLine("451 try again later")
+			} else {
+				_ = text.PrintfLine("250 OK")
+			}
+		case "DATA":
+			_ = text.PrintfLine("354 go ahead")
+			lines, err := text.ReadDotLines()
+			if err != nil {
+				return
+			}
+			s.mutex.Lock()
+			s.messages = append(s.messages, strings.Join(lines, "\n"))
+			s.mutex.Unlock()
+			_ = text.PrintfLine("250 OK")
+		case "QUIT":
+			_ = text.PrintfLine("221 bye")
+			return
+		default:
+			_ = text.PrintfLine("250 OK")
+		}
+	}
+}
+
+func (s *smtpServer) setFailing(failing bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failing = failing
+}
+
+func (s *smtpServer) getMessages() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.messages...)
+}
+
+func newTestAgent(t *testing.T, server *smtpServer) *EmailAgent {
+	host, port, _ := net.SplitHostPort(server.listener.Addr().String())
+	config := viper.New()
+	config.Set("host", host)
+	config.Set("port", port)
+	config.Set("from", "seekerr@example.com")
+	config.Set("to", []string{"me@example.com"})
+	logger := zerolog.Nop()
+	agent := NewEmailAgent(config, &logger)
+	if agent == nil {
+		t.Fatalf("NewEmailAgent() = nil")
+	}
+	return agent
+}
+
+func addedEvent(title string, year int) notification.Event {
+	return notification.Event{
+		Type: notification.ADDED_MOVIE,
+		Data: map[string]interface{}{
+			"name":  "trending",
+			"item":  &provider.ListItem{Title: title, Year: year},
+			"movie": &radarr.Movie{Title: title, Year: year, ImdbId: "tt0133093"},
+		},
+	}
+}
+
+func TestSendEventDigest(t *testing.T) {
+	server := newSmtpServer(t)
+	defer server.listener.Close()
+	agent := newTestAgent(t, server)
+
+	if err := agent.SendEvent(addedEvent("The Matrix", 1999)); err != nil {
+		t.Fatalf("SendEvent() error = %v", err)
+	}
+	// the events restored without movie are skipped
+	invalid := notification.Event{Type: notification.ADDED_MOVIE, Data: map[string]interface{}{"name": "trending"}}
+	if err := agent.SendEvent(invalid); err != nil {
+		t.Fatalf("SendEvent() error = %v", err)
+	}
+	if messages := server.getMessages(); len(messages) != 0 {
+		t.Fatalf("messages before the end of the feeds = %d, expected 0", len(messages))
+	}
+
+	if err := agent.SendEvent(notification.Event{Type: notification.FINISH_ALL_FEEDS}); err != nil {
+		t.Fatalf("SendEvent() error = %v", err)
+	}
+	messages := server.getMessages()
+	if len(messages) != 1 {
+		t.Fatalf("messages = %d, expected 1", len(messages))
+	}
+	if !strings.Contains(messages[0], "The Matrix (1999)") || !strings.Contains(messages[0], "To: me@example.com") {
+		t.Errorf("message without the movie or recipient: %s", messages[0])
+	}
+
+	// the buffer is empty after the digest
+	if err := agent.Flush(); err != nil || len(server.getMessages()) != 1 {
+		t.Errorf("Flush() sent an empty digest, error = %v", err)
+	}
+}
+
+func TestFlushRetry(t *testing.T) {
+	server := newSmtpServer(t)
+	defer server.listener.Close()
+	agent := newTestAgent(t, server)
+
+	server.setFailing(true)
+	_ = agent.SendEvent(addedEvent("The Matrix", 1999))
+	if err := agent.Flush(); err == nil {
+		t.Fatalf("Flush() expected error of the failing server")
+	}
+
+	// the movies are kept for the retry
+	server.setFailing(false)
+	_ = agent.SendEvent(addedEvent("Dune", 2021))
+	if err := agent.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	messages := server.getMessages()
+	if len(messages) != 1 || !strings.Contains(messages[0], "The Matrix (1999)") || !strings.Contains(messages[0], "Dune (2021)") {
+		t.Errorf("messages = %v, expected one with both movies", messages)
+	}
+}
+
+func TestDispatcherClose(t *testing.T) {
+	server := newSmtpServer(t)
+	defer server.listener.Close()
+	agent := newTestAgent(t, server)
+
+	config := viper.New()
+	config.Set("spool", t.TempDir()+"/held.jsonl")
+	config.Set("deadLetter", t.TempDir()+"/dead.jsonl")
+	logger := zerolog.Nop()
+	dispatcher := notification.NewNotificationDispatcher(config, &logger)
+	dispatcher.RegisterAgent(agent, notification.AgentOptions{Mode: notification.MODE_IMMEDIATE})
+
+	// the buffered movies are sent when the dispatcher is closed
+	dispatcher.SendEventAddMovie("trending", &provider.ListItem{Title: "The Matrix", Year: 1999},
+		&radarr.Movie{Title: "The Matrix", Year: 1999, ImdbId: "tt0133093"})
+	dispatcher.Close()
+
+	messages := server.getMessages()
+	if len(messages) != 1 || !strings.Contains(messages[0], "The Matrix (1999)") {
+		t.Errorf("messages = %v, expected the buffered movie", messages)
+	}
+}
@@ -23,16 +23,21 @@
 package gotify
 
 import (
-	"fmt"
 	"github.com/go-resty/resty/v2"
 	"github.com/lightglitch/seekerr/notification"
-	"github.com/lightglitch/seekerr/provider"
-	"github.com/lightglitch/seekerr/services/radarr"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 	"net/url"
 )
 
+// defaultMessages are the plain text ones with the movie link and poster.
+var defaultMessages = notification.MergeMessages(notification.DefaultMessages, map[notification.EventType]string{
+	notification.ADDED_MOVIE: "Added new movie '[{{ .Movie.Title }} ({{ .Movie.Year }})]({{ .Url }})', " +
+		"ratings: imdb {{ printf \"%.1f\" .Item.Ratings.Imdb }}/10, metacritic {{ .Item.Ratings.Metacritic }}/100, " +
+		"rotten tomatoes {{ .Item.Ratings.RottenTomatoes }}%" +
+		"{{ if .Movie.Images }}  ![{{ .Movie.Title }}]({{ (index .Movie.Images 0).URL }}){{ end }}",
+})
+
 func NewGotifyAgent(config *viper.Viper, logger *zerolog.Logger, restyClient *resty.Client) *GotifyAgent {
 
 	url, err := url.Parse(config.GetString("webhook"))
@@ -41,16 +46,31 @@ func NewGotifyAgent(config *viper.Viper, logger *zerolog.Logger, restyClient *re
 		return nil
 	}
 
+	titles, err := notification.NewMessageTemplates(config, "titles", notification.DefaultTitles)
+	if err != nil {
+		logger.Error().Err(err).Msg("Invalid gotify configuration.")
+		return nil
+	}
+	messages, err := notification.NewMessageTemplates(config, "templates", defaultMessages)
+	if err != nil {
+		logger.Error().Err(err).Msg("Invalid gotify configuration.")
+		return nil
+	}
+
 	events := []notification.EventType{}
 	_ = config.UnmarshalKey("events", &events)
 
 	return &GotifyAgent{
-		*notification.NewWebhookAgent(url.String(), events, logger.With().Str("Component", "GotifyAgent").Logger(), restyClient),
+		WebhookAgent: *notification.NewWebhookAgent(url.String(), events, logger.With().Str("Component", "GotifyAgent").Logger(), restyClient),
+		titles:       titles,
+		messages:     messages,
 	}
 }
 
 type GotifyAgent struct {
 	notification.WebhookAgent
+	titles   *notification.MessageTemplates
+	messages *notification.MessageTemplates
 }
 
 func (g *GotifyAgent) Name() string {
@@ -61,52 +81,39 @@ func (g *GotifyAgent) getMessage(event notification.Event) interface{} {
 	g.WebhookAgent.Logger.Debug().Interface("event", event).Msg("Processing message")
 	message := map[string]interface{}{}
 
+	title, err := g.titles.Render(event)
+	if err != nil {
+		g.WebhookAgent.Logger.Warn().Err(err).Interface("event", event).Msg("Rendering title template")
+	}
+	text, err := g.messages.Render(event)
+	if err != nil {
+		g.WebhookAgent.Logger.Warn().Err(err).Interface("event", event).Msg("Rendering message template")
+	}
+	if text == "" {
+		g.WebhookAgent.Logger.Error().Interface("event", event).Msg("Invalid event type")
+		return nil
+	}
+
+	message["title"] = title
+	message["message"] = text
+
 	switch event.Type {
-	case notification.START_FEED:
-		message["title"] = fmt.Sprintf("Seekerr: %s", event.Data["name"])
-		message["message"] = fmt.Sprintf("Start processing feed %s", event.Data["name"])
-	case notification.FINISH_FEED:
-		message["title"] = fmt.Sprintf("Seekerr: %s", event.Data["name"])
-		message["message"] = fmt.Sprintf("Finish processing feed %s, added %d movies", event.Data["name"], event.Data["added"])
-	case notification.FINISH_ALL_FEEDS:
-		message["title"] = "Seekerr"
-		message["message"] = fmt.Sprintf("Finish processing all feeds, added %d movies", event.Data["added"])
 	case notification.ADDED_MOVIE:
-		message["title"] = fmt.Sprintf("Seekerr: %s", event.Data["name"])
-		message["extras"] = map[string]map[string]string {
+		message["extras"] = map[string]map[string]string{
 			"client::display": {
 				"contentType": "text/markdown",
 			},
 		}
-
-		movie := event.Data["movie"].(*radarr.Movie)
-		item := event.Data["item"].(*provider.ListItem)
-		url := fmt.Sprintf("https://www.themoviedb.org/movie/%d-%s", movie.TmdbID, movie.TitleSlug)
-		if movie.ImdbId != "" {
-			url = fmt.Sprintf("https://www.imdb.com/title/%s", movie.ImdbId)
-		}
-		text := fmt.Sprintf("Added new movie '[%s (%d)](%s)', ratings: imdb %.1f/10, metacritic %d/100, rotten tomatoes %d%%",
-			movie.Title, movie.Year, url, item.Ratings.Imdb, item.Ratings.Metacritic, item.Ratings.RottenTomatoes)
-		if len(movie.Images) > 0 {
-			text += fmt.Sprintf("  ![%s](%s)", movie.Title, movie.Images[0].URL)
-		}
-		message["message"] = text
-	case notification.REVISION_MOVIE:
-		message["title"] = fmt.Sprintf("Seekerr: %s", event.Data["name"])
-		movie := event.Data["movie"].(*radarr.Movie)
-		item := event.Data["item"].(*provider.ListItem)
-		message["message"] = fmt.Sprintf("Movie '%s (%d)' for revision, ratings: imdb %.1f/10, metacritic %d/100, rotten tomatoes %d%%",
-			movie.Title, movie.Year, item.Ratings.Imdb, item.Ratings.Metacritic, item.Ratings.RottenTomatoes)
-	default:
-		g.WebhookAgent.Logger.Error().Interface("event", event).Msg("Invalid event type")
-		return nil
+	case notification.LIST_FAILED:
+		message["priority"] = 8
 	}
 
 	return message
 }
 
-func (a *GotifyAgent) SendEvent(event notification.Event) {
+func (a *GotifyAgent) SendEvent(event notification.Event) error {
 	if a.IsSubscribe(event.Type) {
-		a.SendMessage(event, a.getMessage(event))
+		return a.SendMessage(event, a.getMessage(event))
 	}
+	return nil
 }
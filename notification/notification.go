@@ -23,9 +23,17 @@
 package notification
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"github.com/lightglitch/seekerr/provider"
 	"github.com/lightglitch/seekerr/services/radarr"
 	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 )
 
 type EventType string
@@ -36,46 +44,498 @@ const (
 	FINISH_ALL_FEEDS = "FINISH_ALL_FEEDS"
 	ADDED_MOVIE      = "ADDED_MOVIE"
 	REVISION_MOVIE   = "REVISION_MOVIE"
+	LIST_FAILED      = "LIST_FAILED"
+	ITEM_ERROR       = "ITEM_ERROR"
+	DIGEST           = "DIGEST"
+)
+
+const (
+	DEFAULT_SPOOL_FILE       = "var/notifications/held.jsonl"
+	DEFAULT_DEAD_LETTER_FILE = "var/notifications/dead.jsonl"
+	DEFAULT_RETRIES          = 3
+	DEFAULT_BACKOFF          = 2 * time.Second
+	MAX_BACKOFF              = time.Minute
+	QUEUE_SIZE               = 1000
 )
 
 type Event struct {
-	Type EventType
-	Data map[string]interface{}
+	Type EventType              `json:"type"`
+	Data map[string]interface{} `json:"data"`
 }
 
-func NewNotificationDispatcher(logger *zerolog.Logger) *Dispatcher {
-	return &Dispatcher{
-		logger: logger.With().Str("Component", "Notification Dispatcher").Logger(),
-		agents: map[string]Agent{},
+// UnmarshalJSON restores the typed data of the events, so the agents can
+// render the events read from the spool like the ones sent by the importer.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	raw := struct {
+		Type EventType                  `json:"type"`
+		Data map[string]json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	e.Type = raw.Type
+	e.Data = map[string]interface{}{}
+	for key, value := range raw.Data {
+		var err error
+		switch key {
+		case "item":
+			var item *provider.ListItem
+			err = json.Unmarshal(value, &item)
+			e.Data[key] = item
+		case "movie":
+			var movie *radarr.Movie
+			err = json.Unmarshal(value, &movie)
+			e.Data[key] = movie
+		case "events":
+			events := []Event{}
+			err = json.Unmarshal(value, &events)
+			e.Data[key] = events
+		case "approved", "added":
+			count := 0
+			err = json.Unmarshal(value, &count)
+			e.Data[key] = count
+		default:
+			var other interface{}
+			err = json.Unmarshal(value, &other)
+			e.Data[key] = other
+		}
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
+// NewNotificationDispatcher creates the dispatcher, the config is the
+// notifications section and may be nil.
+func NewNotificationDispatcher(config *viper.Viper, logger *zerolog.Logger) *Dispatcher {
+	dispatcher := &Dispatcher{
+		logger:     logger.With().Str("Component", "Notification Dispatcher").Logger(),
+		agents:     map[string]*delivery{},
+		spool:      DEFAULT_SPOOL_FILE,
+		deadLetter: DEFAULT_DEAD_LETTER_FILE,
+		retries:    DEFAULT_RETRIES,
+		backoff:    DEFAULT_BACKOFF,
+	}
+	if config == nil {
+		return dispatcher
+	}
+
+	if config.IsSet("spool") {
+		dispatcher.spool = config.GetString("spool")
+	}
+	if config.IsSet("deadLetter") {
+		dispatcher.deadLetter = config.GetString("deadLetter")
+	}
+	if config.IsSet("retries") {
+		dispatcher.retries = config.GetInt("retries")
+	}
+	if config.IsSet("backoff") {
+		dispatcher.backoff = config.GetDuration("backoff")
+	}
+	return dispatcher
+}
+
+// Dispatcher delivers the events to the agents, each agent has a queue and a
+// worker so a slow or failing service doesn't stall the import.
 type Dispatcher struct {
-	logger zerolog.Logger
-	agents map[string]Agent
+	logger     zerolog.Logger
+	agents     map[string]*delivery
+	spool      string
+	deadLetter string
+	retries    int
+	backoff    time.Duration
+	closed     bool
+	mutex      sync.Mutex
+	deadMutex  sync.Mutex
+	workers    sync.WaitGroup
+}
+
+// delivery keeps the events of one agent that are waiting for the digest
+// or for the end of the quiet hours.
+type delivery struct {
+	agent   Agent
+	options AgentOptions
+	digest  []Event
+	held    []Event
+	timer   *time.Timer
+	queue   chan Event
 }
 
-func (d *Dispatcher) RegisterAgent(agent Agent) {
-	if agent != nil {
-		d.logger.Info().Str("agent", agent.Name()).Msg("Register agent")
-		d.agents[agent.Name()] = agent
+type spooledEvent struct {
+	Agent string `json:"agent"`
+	Event Event  `json:"event"`
+}
+
+func (d *Dispatcher) RegisterAgent(agent Agent, options AgentOptions) {
+	if agent == nil {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, ok := d.agents[agent.Name()]; ok || d.closed {
+		d.logger.Warn().Str("agent", agent.Name()).Msg("Agent can't be registered")
+		return
+	}
+
+	d.logger.Info().Str("agent", agent.Name()).Str("mode", options.Mode).Msg("Register agent")
+	target := &delivery{agent: agent, options: options, queue: make(chan Event, QUEUE_SIZE)}
+	d.agents[agent.Name()] = target
+
+	d.workers.Add(1)
+	go d.work(target)
+
+	held, err := d.unspool(agent.Name())
+	if err != nil {
+		d.logger.Error().Err(err).Str("agent", agent.Name()).Msg("Reading held events")
 	}
+	target.held = held
+	d.release(target)
 }
 
 func (d *Dispatcher) SendEvent(event Event) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		d.logger.Warn().Interface("event", event).Msg("Dispatcher is closed")
+		return
+	}
+
 	d.logger.Info().Interface("event", event).Int("agents", len(d.agents)).Msg("Broadcast event")
-	for _, agent := range d.agents {
-		agent.SendEvent(event)
+	for _, target := range d.agents {
+		if target.options.Mode == MODE_DIGEST {
+			d.collect(target, event)
+		} else {
+			d.deliver(target, event)
+		}
+	}
+}
+
+// collect adds the event to the digest of the agent, sending the digest
+// when the configured finish event arrives.
+func (d *Dispatcher) collect(target *delivery, event Event) {
+	switch event.Type {
+	case START_FEED:
+		return
+	case FINISH_FEED, FINISH_ALL_FEEDS:
+		if event.Type == target.options.DigestOn || event.Type == FINISH_ALL_FEEDS {
+			name := ""
+			if event.Type == FINISH_FEED {
+				name, _ = event.Data["name"].(string)
+			}
+			d.flushDigest(target, name)
+		}
+		return
+	}
+
+	if isSubscribed(target.agent, event.Type) {
+		target.digest = append(target.digest, event)
 	}
 }
 
+func (d *Dispatcher) flushDigest(target *delivery, name string) {
+	if len(target.digest) == 0 {
+		return
+	}
+
+	data := map[string]interface{}{
+		"events": target.digest,
+	}
+	if name != "" {
+		data["name"] = name
+	}
+	target.digest = nil
+	d.deliver(target, Event{Type: DIGEST, Data: data})
+}
+
+// deliver sends the event to the agent or holds it during the quiet hours.
+func (d *Dispatcher) deliver(target *delivery, event Event) {
+	if target.isQuiet() {
+		target.held = append(target.held, event)
+		d.schedule(target)
+		return
+	}
+
+	d.release(target)
+	d.enqueue(target, event)
+}
+
+// enqueue never blocks, a full queue means the worker of the agent is stuck
+// retrying and the event goes to the dead letter log instead of stalling the
+// other agents and the import.
+func (d *Dispatcher) enqueue(target *delivery, event Event) {
+	select {
+	case target.queue <- event:
+	default:
+		d.logger.Warn().Str("agent", target.agent.Name()).Str("event", string(event.Type)).Msg("Queue is full")
+		if err := d.saveDeadLetter(target.agent.Name(), event, errors.New("queue is full")); err != nil {
+			d.logger.Error().Err(err).Msg("Saving undeliverable event")
+		}
+	}
+}
+
+func (t *delivery) isQuiet() bool {
+	return t.options.QuietHours != nil && t.options.QuietHours.Contains(time.Now())
+}
+
+// schedule releases the held events of the agent when the quiet hours end.
+func (d *Dispatcher) schedule(target *delivery) {
+	if target.timer != nil {
+		return
+	}
+	wait := target.options.QuietHours.Until(time.Now())
+	d.logger.Debug().Str("agent", target.agent.Name()).Dur("wait", wait).Msg("Holding events")
+	target.timer = time.AfterFunc(wait, func() {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		if !d.closed {
+			target.timer = nil
+			d.release(target)
+		}
+	})
+}
+
+// release sends the held events when outside the quiet hours.
+func (d *Dispatcher) release(target *delivery) {
+	if len(target.held) == 0 {
+		return
+	}
+	if target.isQuiet() {
+		d.schedule(target)
+		return
+	}
+
+	held := target.held
+	target.held = nil
+	d.logger.Info().Str("agent", target.agent.Name()).Int("events", len(held)).Msg("Releasing held events")
+	for _, event := range held {
+		d.enqueue(target, event)
+	}
+}
+
+// Close sends the pending digests, waits for the queued events and saves the
+// events still held by the quiet hours in the spool, they are sent by the
+// next run after the window.
+func (d *Dispatcher) Close() {
+	d.mutex.Lock()
+	if d.closed {
+		d.mutex.Unlock()
+		return
+	}
+
+	pending := []spooledEvent{}
+	for name, target := range d.agents {
+		d.flushDigest(target, "")
+		if target.timer != nil {
+			target.timer.Stop()
+			target.timer = nil
+		}
+		for _, event := range target.held {
+			pending = append(pending, spooledEvent{Agent: name, Event: event})
+		}
+		target.held = nil
+		close(target.queue)
+	}
+	d.closed = true
+	d.mutex.Unlock()
+
+	if err := d.saveSpool(pending); err != nil {
+		d.logger.Error().Err(err).Int("events", len(pending)).Msg("Saving held events")
+	}
+
+	d.logger.Debug().Msg("Waiting for the queued events")
+	d.workers.Wait()
+}
+
+// work sends the queued events of the agent in order, and the events
+// buffered by the agent when the queue is closed.
+func (d *Dispatcher) work(target *delivery) {
+	defer d.workers.Done()
+	for event := range target.queue {
+		d.send(target.agent, event)
+	}
+	if flusher, ok := target.agent.(Flusher); ok {
+		if err := d.retry(target.agent.Name(), "flush", flusher.Flush); err != nil {
+			d.logger.Error().Err(err).Str("agent", target.agent.Name()).Msg("Undeliverable buffered events")
+		}
+	}
+}
+
+// send retries the event, the events that can't be delivered are saved in
+// the dead letter log.
+func (d *Dispatcher) send(agent Agent, event Event) {
+	err := d.retry(agent.Name(), string(event.Type), func() error {
+		return agent.SendEvent(event)
+	})
+	if err != nil {
+		d.logger.Error().Err(err).Str("agent", agent.Name()).Str("event", string(event.Type)).Msg("Undeliverable event")
+		if err := d.saveDeadLetter(agent.Name(), event, err); err != nil {
+			d.logger.Error().Err(err).Msg("Saving undeliverable event")
+		}
+	}
+}
+
+// retry calls send with exponential backoff until it succeeds, the error is
+// permanent or there are no retries left.
+func (d *Dispatcher) retry(agent string, event string, send func() error) error {
+	wait := d.backoff
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil || attempt > d.retries || IsPermanent(err) {
+			return err
+		}
+
+		d.logger.Warn().Err(err).Str("agent", agent).Str("event", event).Int("attempt", attempt).Dur("retry", wait).Msg("Error sending event")
+		time.Sleep(wait)
+		wait *= 2
+		if wait > MAX_BACKOFF {
+			wait = MAX_BACKOFF
+		}
+	}
+}
+
+type deadEvent struct {
+	Time  time.Time `json:"time"`
+	Agent string    `json:"agent"`
+	Error string    `json:"error"`
+	Event Event     `json:"event"`
+}
+
+func (d *Dispatcher) saveDeadLetter(agent string, event Event, cause error) error {
+	d.deadMutex.Lock()
+	defer d.deadMutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(d.deadLetter), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(d.deadLetter, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewEncoder(file).Encode(deadEvent{
+		Time:  time.Now(),
+		Agent: agent,
+		Error: cause.Error(),
+		Event: event,
+	})
+}
+
+func (d *Dispatcher) readSpool() ([]spooledEvent, error) {
+	file, err := os.Open(d.spool)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	events := []spooledEvent{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		event := spooledEvent{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			d.logger.Warn().Err(err).Msg("Skipping invalid held event")
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+func (d *Dispatcher) writeSpool(events []spooledEvent) error {
+	if len(events) == 0 {
+		if err := os.Remove(d.spool); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.spool), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(d.spool)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unspool removes and returns the held events of the agent.
+func (d *Dispatcher) unspool(agent string) ([]Event, error) {
+	spooled, err := d.readSpool()
+	if err != nil || len(spooled) == 0 {
+		return nil, err
+	}
+
+	held := []Event{}
+	others := []spooledEvent{}
+	for _, event := range spooled {
+		if event.Agent == agent {
+			held = append(held, event.Event)
+		} else {
+			others = append(others, event)
+		}
+	}
+	if len(held) > 0 {
+		err = d.writeSpool(others)
+	}
+	return held, err
+}
+
+// saveSpool keeps the events of the agents that were not registered in this
+// run and adds the new ones.
+func (d *Dispatcher) saveSpool(pending []spooledEvent) error {
+	spooled, err := d.readSpool()
+	if err != nil {
+		return err
+	}
+	if len(spooled) == 0 && len(pending) == 0 {
+		return nil
+	}
+	return d.writeSpool(append(spooled, pending...))
+}
+
+// copyItem and copyMovie detach the data of the events from the importer,
+// the events are rendered later by the workers of the agents.
+func copyItem(item *provider.ListItem) *provider.ListItem {
+	if item == nil {
+		return nil
+	}
+	copied := *item
+	return &copied
+}
+
+func copyMovie(movie *radarr.Movie) *radarr.Movie {
+	if movie == nil {
+		return nil
+	}
+	copied := *movie
+	return &copied
+}
+
 func (d *Dispatcher) SendEventAddMovie(name string, item *provider.ListItem, movie *radarr.Movie) {
 	d.SendEvent(Event{
 		Type: ADDED_MOVIE,
 		Data: map[string]interface{}{
 			"name":  name,
-			"item":  item,
-			"movie": movie,
+			"item":  copyItem(item),
+			"movie": copyMovie(movie),
 		},
 	})
 }
@@ -85,8 +545,29 @@ func (d *Dispatcher) SendEventRevisionMovie(name string, item *provider.ListItem
 		Type: REVISION_MOVIE,
 		Data: map[string]interface{}{
 			"name":  name,
-			"item":  item,
-			"movie": movie,
+			"item":  copyItem(item),
+			"movie": copyMovie(movie),
+		},
+	})
+}
+
+func (d *Dispatcher) SendEventListFailed(name string, err error) {
+	d.SendEvent(Event{
+		Type: LIST_FAILED,
+		Data: map[string]interface{}{
+			"name":  name,
+			"error": err.Error(),
+		},
+	})
+}
+
+func (d *Dispatcher) SendEventItemError(name string, item *provider.ListItem, err error) {
+	d.SendEvent(Event{
+		Type: ITEM_ERROR,
+		Data: map[string]interface{}{
+			"name":  name,
+			"item":  copyItem(item),
+			"error": err.Error(),
 		},
 	})
 }
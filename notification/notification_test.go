@@ -0,0 +1,340 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package notification
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/services/radarr"
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// testAgent keeps the events it receives and fails with the queued errors.
+type testAgent struct {
+	name       string
+	subscribed []EventType
+	mutex      sync.Mutex
+	events     []Event
+	errors     []error
+	calls      int
+}
+
+func (a *testAgent) Name() string {
+	return a.name
+}
+
+func (a *testAgent) IsSubscribe(eventType EventType) bool {
+	if len(a.subscribed) == 0 || eventType == DIGEST {
+		return true
+	}
+	for _, subscribed := range a.subscribed {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *testAgent) SendEvent(event Event) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.calls++
+	if len(a.errors) > 0 {
+		err := a.errors[0]
+		a.errors = a.errors[1:]
+		return err
+	}
+	a.events = append(a.events, event)
+	return nil
+}
+
+func newTestDispatcher(t *testing.T) *Dispatcher {
+	logger := zerolog.Nop()
+	dispatcher := NewNotificationDispatcher(nil, &logger)
+	dispatcher.spool = filepath.Join(t.TempDir(), "held.jsonl")
+	dispatcher.deadLetter = filepath.Join(t.TempDir(), "dead.jsonl")
+	dispatcher.backoff = 0
+	return dispatcher
+}
+
+// summary describes the events as their types, with the types of the
+// events of the digests and their list.
+func summary(events []Event) []string {
+	result := []string{}
+	for _, event := range events {
+		line := string(event.Type)
+		if event.Type == DIGEST {
+			if name, ok := event.Data["name"]; ok {
+				line += " " + name.(string)
+			}
+			for _, digested := range event.Data["events"].([]Event) {
+				line += " " + string(digested.Type) + ":" + movieTitle(digested)
+			}
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+func TestDigest(t *testing.T) {
+	dispatcher := newTestDispatcher(t)
+	perFeed := &testAgent{name: "feed"}
+	allFeeds := &testAgent{name: "all", subscribed: []EventType{ADDED_MOVIE}}
+	immediate := &testAgent{name: "immediate"}
+	dispatcher.RegisterAgent(perFeed, AgentOptions{Mode: MODE_DIGEST, DigestOn: FINISH_FEED})
+	dispatcher.RegisterAgent(allFeeds, AgentOptions{Mode: MODE_DIGEST, DigestOn: FINISH_ALL_FEEDS})
+	dispatcher.RegisterAgent(immediate, AgentOptions{Mode: MODE_IMMEDIATE})
+
+	dispatcher.SendEventStartFeed("trakt")
+	dispatcher.SendEventAddMovie("trakt", &provider.ListItem{Title: "The Matrix", Year: 1999}, &radarr.Movie{Title: "The Matrix", Year: 1999})
+	dispatcher.SendEventItemError("trakt", &provider.ListItem{Title: "Dune", Year: 2021}, errors.New("not found"))
+	dispatcher.SendEventEndFeed("trakt", 1, 1)
+	dispatcher.SendEventStartFeed("imdb")
+	dispatcher.SendEventAddMovie("imdb", &provider.ListItem{Title: "Amélie", Year: 2001}, &radarr.Movie{Title: "Amélie", Year: 2001})
+	dispatcher.SendEventEndFeed("imdb", 1, 1)
+	dispatcher.SendEventStartFeed("rss")
+	dispatcher.SendEventEndFeed("rss", 0, 0)
+	dispatcher.SendEventEndAllFeeds(2, 2)
+	dispatcher.Close()
+
+	tests := []struct {
+		agent    *testAgent
+		expected []string
+	}{
+		{agent: perFeed, expected: []string{
+			"DIGEST trakt ADDED_MOVIE:The Matrix (1999) ITEM_ERROR:Dune (2021)",
+			"DIGEST imdb ADDED_MOVIE:Amélie (2001)",
+		}},
+		{agent: allFeeds, expected: []string{
+			"DIGEST ADDED_MOVIE:The Matrix (1999) ADDED_MOVIE:Amélie (2001)",
+		}},
+		{agent: immediate, expected: []string{
+			START_FEED, ADDED_MOVIE, ITEM_ERROR, FINISH_FEED, START_FEED, ADDED_MOVIE, FINISH_FEED,
+			START_FEED, FINISH_FEED, FINISH_ALL_FEEDS,
+		}},
+	}
+
+	for _, test := range tests {
+		if result := summary(test.agent.events); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("agent %s received %q, expected %q", test.agent.name, result, test.expected)
+		}
+	}
+}
+
+func TestDigestOnClose(t *testing.T) {
+	dispatcher := newTestDispatcher(t)
+	agent := &testAgent{name: "feed"}
+	dispatcher.RegisterAgent(agent, AgentOptions{Mode: MODE_DIGEST, DigestOn: FINISH_FEED})
+
+	dispatcher.SendEventListFailed("trakt", errors.New("unauthorized"))
+	dispatcher.Close()
+
+	expected := []string{"DIGEST LIST_FAILED:"}
+	if result := summary(agent.events); !reflect.DeepEqual(result, expected) {
+		t.Errorf("agent received %q, expected %q", result, expected)
+	}
+}
+
+func readDeadLetters(t *testing.T, dispatcher *Dispatcher) []deadEvent {
+	file, err := os.Open(dispatcher.deadLetter)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	events := []deadEvent{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		event := deadEvent{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestRetry(t *testing.T) {
+	dispatcher := newTestDispatcher(t)
+	agent := &testAgent{name: "retry", errors: []error{errors.New("timeout"), errors.New("timeout")}}
+	dispatcher.RegisterAgent(agent, AgentOptions{Mode: MODE_IMMEDIATE})
+
+	dispatcher.SendEventStartFeed("trakt")
+	dispatcher.Close()
+
+	if agent.calls != 3 || len(agent.events) != 1 {
+		t.Errorf("agent called %d times with %d events, expected 3 calls and 1 event", agent.calls, len(agent.events))
+	}
+	if dead := readDeadLetters(t, dispatcher); len(dead) != 0 {
+		t.Errorf("dead letters %+v, expected none", dead)
+	}
+}
+
+func TestRetryPermanent(t *testing.T) {
+	dispatcher := newTestDispatcher(t)
+	agent := &testAgent{name: "permanent", errors: []error{Permanent(errors.New("invalid url"))}}
+	dispatcher.RegisterAgent(agent, AgentOptions{Mode: MODE_IMMEDIATE})
+
+	dispatcher.SendEventStartFeed("trakt")
+	dispatcher.Close()
+
+	if agent.calls != 1 {
+		t.Errorf("agent called %d times, expected 1", agent.calls)
+	}
+	dead := readDeadLetters(t, dispatcher)
+	if len(dead) != 1 || dead[0].Agent != "permanent" || dead[0].Error != "invalid url" || dead[0].Event.Type != START_FEED {
+		t.Errorf("dead letters %+v, expected the start feed event", dead)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	dispatcher := newTestDispatcher(t)
+	failures := []error{}
+	for attempt := 0; attempt <= DEFAULT_RETRIES; attempt++ {
+		failures = append(failures, errors.New("503 Service Unavailable"))
+	}
+	agent := &testAgent{name: "failing", errors: failures}
+	dispatcher.RegisterAgent(agent, AgentOptions{Mode: MODE_IMMEDIATE})
+
+	dispatcher.SendEventAddMovie("trakt", &provider.ListItem{Title: "The Matrix", Year: 1999}, &radarr.Movie{Title: "The Matrix", Year: 1999})
+	dispatcher.SendEventEndFeed("trakt", 1, 1)
+	dispatcher.Close()
+
+	if agent.calls != DEFAULT_RETRIES+2 {
+		t.Errorf("agent called %d times, expected %d", agent.calls, DEFAULT_RETRIES+2)
+	}
+	if result := summary(agent.events); !reflect.DeepEqual(result, []string{FINISH_FEED}) {
+		t.Errorf("agent received %q, expected the finish feed event", result)
+	}
+
+	dead := readDeadLetters(t, dispatcher)
+	if len(dead) != 1 || dead[0].Agent != "failing" || dead[0].Error != "503 Service Unavailable" {
+		t.Fatalf("dead letters %+v, expected the added movie event", dead)
+	}
+	if movie, ok := dead[0].Event.Data["movie"].(*radarr.Movie); !ok || movie.Title != "The Matrix" {
+		t.Errorf("dead letter movie %v, expected The Matrix", dead[0].Event.Data["movie"])
+	}
+}
+
+// blockingAgent is stuck sending the first event until released.
+type blockingAgent struct {
+	testAgent
+	release chan bool
+}
+
+func (a *blockingAgent) SendEvent(event Event) error {
+	<-a.release
+	return a.testAgent.SendEvent(event)
+}
+
+func TestFullQueue(t *testing.T) {
+	dispatcher := newTestDispatcher(t)
+	stuck := &blockingAgent{testAgent: testAgent{name: "stuck"}, release: make(chan bool)}
+	dispatcher.RegisterAgent(stuck, AgentOptions{Mode: MODE_IMMEDIATE})
+
+	sent := QUEUE_SIZE + 10
+	for index := 0; index < sent; index++ {
+		dispatcher.SendEventStartFeed("trakt")
+	}
+
+	// the other agents still get the events while the stuck one is full
+	other := &testAgent{name: "other"}
+	dispatcher.RegisterAgent(other, AgentOptions{Mode: MODE_IMMEDIATE})
+	dispatcher.SendEventEndAllFeeds(0, 0)
+	sent++
+
+	close(stuck.release)
+	dispatcher.Close()
+
+	if result := summary(other.events); !reflect.DeepEqual(result, []string{FINISH_ALL_FEEDS}) {
+		t.Errorf("other agent received %q, expected the finish all feeds event", result)
+	}
+	dead := readDeadLetters(t, dispatcher)
+	if len(dead) == 0 || len(stuck.events)+len(dead) != sent {
+		t.Errorf("stuck agent received %d events and %d dead letters, expected %d", len(stuck.events), len(dead), sent)
+	}
+	for _, event := range dead {
+		if event.Agent != "stuck" || event.Error != "queue is full" {
+			t.Errorf("dead letter %+v, expected the full queue of the stuck agent", event)
+		}
+	}
+}
+
+func TestSpool(t *testing.T) {
+	dispatcher := newTestDispatcher(t)
+	item := &provider.ListItem{Title: "The Matrix", Year: 1999, Imdb: "tt0133093"}
+	movie := &radarr.Movie{Title: "The Matrix", Year: 1999, TmdbID: 603}
+	pending := []spooledEvent{
+		{Agent: "slack", Event: Event{Type: ADDED_MOVIE, Data: map[string]interface{}{"name": "trakt", "item": item, "movie": movie}}},
+		{Agent: "email", Event: Event{Type: FINISH_ALL_FEEDS, Data: map[string]interface{}{"approved": 1, "added": 1}}},
+		{Agent: "slack", Event: Event{Type: DIGEST, Data: map[string]interface{}{
+			"events": []Event{{Type: LIST_FAILED, Data: map[string]interface{}{"name": "imdb", "error": "timeout"}}},
+		}}},
+	}
+	if err := dispatcher.saveSpool(pending); err != nil {
+		t.Fatal(err)
+	}
+
+	held, err := dispatcher.unspool("slack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(held) != 2 {
+		t.Fatalf("unspool returned %d events, expected 2", len(held))
+	}
+	if result, ok := held[0].Data["item"].(*provider.ListItem); !ok || !reflect.DeepEqual(result, item) {
+		t.Errorf("unspool item %v, expected %v", held[0].Data["item"], item)
+	}
+	if result, ok := held[0].Data["movie"].(*radarr.Movie); !ok || !reflect.DeepEqual(result, movie) {
+		t.Errorf("unspool movie %v, expected %v", held[0].Data["movie"], movie)
+	}
+	if lines := DigestLines(held[1]); !reflect.DeepEqual(lines, []string{"Failed processing feed imdb: timeout"}) {
+		t.Errorf("unspool digest lines %q", lines)
+	}
+
+	// the events of the other agents stay in the spool
+	others, err := dispatcher.readSpool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(others) != 1 || others[0].Agent != "email" || others[0].Event.Data["added"] != 1 {
+		t.Errorf("spool kept %+v, expected the email event", others)
+	}
+
+	if held, err := dispatcher.unspool("email"); err != nil || len(held) != 1 {
+		t.Errorf("unspool returned %d events and %v, expected 1", len(held), err)
+	}
+	if _, err := os.Stat(dispatcher.spool); !os.IsNotExist(err) {
+		t.Errorf("spool file not removed when empty: %v", err)
+	}
+}
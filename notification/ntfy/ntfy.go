@@ -0,0 +1,170 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package ntfy
+
+import (
+	"encoding/base64"
+	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/notification"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"net/url"
+	"strings"
+)
+
+const (
+	PRIORITY_DEFAULT = 3
+	PRIORITY_HIGH    = 4
+)
+
+// NtfyMessage is published as json to the root of the server, see
+// https://docs.ntfy.sh/publish/#publish-as-json
+type NtfyMessage struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title,omitempty"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Click    string   `json:"click,omitempty"`
+	Attach   string   `json:"attach,omitempty"`
+}
+
+func NewNtfyAgent(config *viper.Viper, logger *zerolog.Logger, restyClient *resty.Client) *NtfyAgent {
+
+	agentLogger := logger.With().Str("Component", "NtfyAgent").Logger()
+
+	// the topic url, like https://ntfy.sh/seekerr
+	topicUrl, err := url.Parse(config.GetString("url"))
+	topic := ""
+	if err == nil {
+		topic = strings.Trim(topicUrl.Path, "/")
+	}
+	if err != nil || topicUrl.Host == "" || topic == "" || strings.Contains(topic, "/") {
+		agentLogger.Error().Err(err).Str("url", config.GetString("url")).Msg("Invalid ntfy topic url configuration.")
+		return nil
+	}
+	topicUrl.Path = "/"
+
+	titles, err := notification.NewMessageTemplates(config, "titles", notification.DefaultTitles)
+	if err != nil {
+		agentLogger.Error().Err(err).Msg("Invalid ntfy configuration.")
+		return nil
+	}
+	messages, err := notification.NewMessageTemplates(config, "templates", notification.DefaultMessages)
+	if err != nil {
+		agentLogger.Error().Err(err).Msg("Invalid ntfy configuration.")
+		return nil
+	}
+
+	events := []notification.EventType{}
+	_ = config.UnmarshalKey("events", &events)
+
+	agent := &NtfyAgent{
+		WebhookAgent: *notification.NewWebhookAgent(topicUrl.String(), events, agentLogger, restyClient),
+		topic:        topic,
+		priority:     PRIORITY_DEFAULT,
+		tags:         config.GetStringSlice("tags"),
+		attach:       true,
+		titles:       titles,
+		messages:     messages,
+	}
+	if config.IsSet("priority") {
+		agent.priority = config.GetInt("priority")
+	}
+	if config.IsSet("attachPoster") {
+		agent.attach = config.GetBool("attachPoster")
+	}
+
+	if token := config.GetString("token"); token != "" {
+		agent.Headers["Authorization"] = "Bearer " + token
+	} else if username := config.GetString("username"); username != "" {
+		credentials := username + ":" + config.GetString("password")
+		agent.Headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+
+	return agent
+}
+
+// NtfyAgent publishes the events to a ntfy topic, the movies open the IMDb
+// page on click and have the poster attached.
+type NtfyAgent struct {
+	notification.WebhookAgent
+	topic    string
+	priority int
+	tags     []string
+	attach   bool
+	titles   *notification.MessageTemplates
+	messages *notification.MessageTemplates
+}
+
+func (a *NtfyAgent) Name() string {
+	return "ntfy"
+}
+
+func (a *NtfyAgent) getMessage(event notification.Event) interface{} {
+	a.WebhookAgent.Logger.Debug().Interface("event", event).Msg("Processing message")
+
+	title, err := a.titles.Render(event)
+	if err != nil {
+		a.WebhookAgent.Logger.Warn().Err(err).Interface("event", event).Msg("Rendering title template")
+	}
+	text, err := a.messages.Render(event)
+	if err != nil {
+		a.WebhookAgent.Logger.Warn().Err(err).Interface("event", event).Msg("Rendering message template")
+	}
+	if text == "" {
+		a.WebhookAgent.Logger.Error().Interface("event", event).Msg("Invalid event type")
+		return nil
+	}
+
+	message := NtfyMessage{
+		Topic:    a.topic,
+		Title:    title,
+		Message:  text,
+		Priority: a.priority,
+		Tags:     a.tags,
+	}
+
+	data := notification.NewTemplateData(event)
+	switch event.Type {
+	case notification.ADDED_MOVIE, notification.REVISION_MOVIE:
+		message.Click = data.Url
+		if a.attach {
+			message.Attach = data.Poster
+		}
+	case notification.LIST_FAILED, notification.ITEM_ERROR:
+		if message.Priority < PRIORITY_HIGH {
+			message.Priority = PRIORITY_HIGH
+		}
+		message.Tags = append([]string{"warning"}, a.tags...)
+	}
+
+	return message
+}
+
+func (a *NtfyAgent) SendEvent(event notification.Event) error {
+	if a.IsSubscribe(event.Type) {
+		return a.SendMessage(event, a.getMessage(event))
+	}
+	return nil
+}
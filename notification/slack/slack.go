@@ -23,14 +23,13 @@
 package slack
 
 import (
-	"fmt"
 	"github.com/go-resty/resty/v2"
 	"github.com/lightglitch/seekerr/notification"
-	"github.com/lightglitch/seekerr/provider"
 	"github.com/lightglitch/seekerr/services/radarr"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 	"net/url"
+	"strings"
 )
 
 type SlackMessage struct {
@@ -49,6 +48,20 @@ type SlackText struct {
 	Text string `json:"text"`
 }
 
+const ratings = "IMDB: *{{ printf \"%.1f\" .Item.Ratings.Imdb }}*/10 | METACRITIC: *{{ .Item.Ratings.Metacritic }}*/100 | " +
+	"ROTTEN TOMATOES: *{{ .Item.Ratings.RottenTomatoes }}%*"
+
+// defaultMessages are rendered as mrkdwn, each paragraph is sent in one section.
+var defaultMessages = notification.MergeMessages(notification.DefaultMessages, map[notification.EventType]string{
+	notification.ADDED_MOVIE: "Added new movie found in feed *{{ .Name }}*.\n\n" +
+		"<{{ .Url }}|{{ .Movie.Title }} ({{ .Movie.Year }})>\n{{ .Movie.Overview }}\n\n" + ratings,
+	notification.REVISION_MOVIE: "Movie for revision found in feed *{{ .Name }}*.\n\n" +
+		"<{{ .TmdbUrl }}|{{ .Movie.Title }} ({{ .Movie.Year }})>\n{{ .Movie.Overview }}\n\n" + ratings,
+	notification.LIST_FAILED: ":warning: Failed processing feed *{{ .Name }}*: {{ .Error }}",
+	notification.ITEM_ERROR:  "Error processing movie '{{ .Item.Title }} ({{ .Item.Year }})' in feed *{{ .Name }}*: {{ .Error }}",
+	notification.DIGEST:      "*{{ .Summary }}*\n\n{{ join .Lines \"\\n\" }}",
+})
+
 func NewSlackAgent(config *viper.Viper, logger *zerolog.Logger, restyClient *resty.Client) *SlackAgent {
 
 	url, err := url.Parse(config.GetString("webhook"))
@@ -57,16 +70,24 @@ func NewSlackAgent(config *viper.Viper, logger *zerolog.Logger, restyClient *res
 		return nil
 	}
 
+	messages, err := notification.NewMessageTemplates(config, "templates", defaultMessages)
+	if err != nil {
+		logger.Error().Err(err).Msg("Invalid slack configuration.")
+		return nil
+	}
+
 	events := []notification.EventType{}
 	_ = config.UnmarshalKey("events", &events)
 
 	return &SlackAgent{
-		*notification.NewWebhookAgent(url.String(), events, logger.With().Str("Component", "SlackAgent").Logger(), restyClient),
+		WebhookAgent: *notification.NewWebhookAgent(url.String(), events, logger.With().Str("Component", "SlackAgent").Logger(), restyClient),
+		messages:     messages,
 	}
 }
 
 type SlackAgent struct {
 	notification.WebhookAgent
+	messages *notification.MessageTemplates
 }
 
 func (g *SlackAgent) Name() string {
@@ -77,107 +98,44 @@ func (g *SlackAgent) getMessage(event notification.Event) interface{} {
 	g.WebhookAgent.Logger.Debug().Interface("event", event).Msg("Processing message")
 	message := SlackMessage{Blocks: []SlackBlock{}}
 
-	switch event.Type {
-	case notification.START_FEED:
-		message.Blocks = append(message.Blocks, SlackBlock{
-			Type: "section",
-			Text: &SlackText{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("Start processing feed %s", event.Data["name"]),
-			},
-		})
-	case notification.FINISH_FEED:
-		message.Blocks = append(message.Blocks, SlackBlock{
-			Type: "section",
-			Text: &SlackText{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("Finish processing feed %s, added %d movies", event.Data["name"], event.Data["added"]),
-			},
-		})
-	case notification.FINISH_ALL_FEEDS:
-		message.Blocks = append(message.Blocks, SlackBlock{
-			Type: "section",
-			Text: &SlackText{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("Finish processing all feeds, added %d movies", event.Data["added"]),
-			},
-		})
-	case notification.ADDED_MOVIE:
-		movie := event.Data["movie"].(*radarr.Movie)
-		item := event.Data["item"].(*provider.ListItem)
-		url := fmt.Sprintf("https://www.themoviedb.org/movie/%d-%s", movie.TmdbID, movie.TitleSlug)
-		if movie.ImdbId != "" {
-			url = fmt.Sprintf("https://www.imdb.com/title/%s", movie.ImdbId)
-		}
+	text, err := g.messages.Render(event)
+	if err != nil {
+		g.WebhookAgent.Logger.Warn().Err(err).Interface("event", event).Msg("Rendering message template")
+	}
+	if text == "" {
+		g.WebhookAgent.Logger.Error().Interface("event", event).Msg("Invalid event type")
+		return nil
+	}
 
-		message.Blocks = append(message.Blocks, SlackBlock{
-			Type: "section",
-			Text: &SlackText{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("Added new movie found in feed *%s*.", event.Data["name"]),
-			},
-		}, SlackBlock{
-			Type: "section",
-			Text: &SlackText{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("<%s|%s (%d)>\n%s", url, movie.Title, movie.Year, movie.Overview),
-			},
-		}, SlackBlock{
-			Type: "section",
-			Text: &SlackText{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("IMDB: *%.1f*/10 | METACRITIC: *%d*/100 | ROTTEN TOMATOES: *%d%%*", item.Ratings.Imdb, item.Ratings.Metacritic, item.Ratings.RottenTomatoes),
-			},
-		})
-		if len(movie.Images) > 0 {
-			message.Blocks = append(message.Blocks, SlackBlock{
-				Type:     "image",
-				ImageURL: movie.Images[0].URL,
-				AltText:  movie.Title,
-			})
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if strings.TrimSpace(paragraph) == "" {
+			continue
 		}
-	case notification.REVISION_MOVIE:
-		movie := event.Data["movie"].(*radarr.Movie)
-		item := event.Data["item"].(*provider.ListItem)
-		url := fmt.Sprintf("https://www.themoviedb.org/movie/%d-%s", movie.TmdbID, movie.TitleSlug)
-
 		message.Blocks = append(message.Blocks, SlackBlock{
 			Type: "section",
 			Text: &SlackText{
 				Type: "mrkdwn",
-				Text: fmt.Sprintf("Movie for revision found in feed *%s*.", event.Data["name"]),
-			},
-		}, SlackBlock{
-			Type: "section",
-			Text: &SlackText{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("<%s|%s (%d)>\n%s", url, movie.Title, movie.Year, movie.Overview),
-			},
-		}, SlackBlock{
-			Type: "section",
-			Text: &SlackText{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("IMDB: *%.1f*/10 | METACRITIC: *%d*/100 | ROTTEN TOMATOES: *%d%%*", item.Ratings.Imdb, item.Ratings.Metacritic, item.Ratings.RottenTomatoes),
+				Text: paragraph,
 			},
 		})
-		if len(movie.Images) > 0 {
+	}
+
+	if event.Type == notification.ADDED_MOVIE || event.Type == notification.REVISION_MOVIE {
+		if movie, ok := event.Data["movie"].(*radarr.Movie); ok && len(movie.Images) > 0 {
 			message.Blocks = append(message.Blocks, SlackBlock{
 				Type:     "image",
 				ImageURL: movie.Images[0].URL,
 				AltText:  movie.Title,
 			})
 		}
-
-	default:
-		g.WebhookAgent.Logger.Error().Interface("event", event).Msg("Invalid event type")
-		return nil
 	}
 
 	return message
 }
 
-func (a *SlackAgent) SendEvent(event notification.Event) {
+func (a *SlackAgent) SendEvent(event notification.Event) error {
 	if a.IsSubscribe(event.Type) {
-		a.SendMessage(event, a.getMessage(event))
+		return a.SendMessage(event, a.getMessage(event))
 	}
+	return nil
 }
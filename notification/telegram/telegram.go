@@ -0,0 +1,207 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package telegram
+
+import (
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/notification"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/services/radarr"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	TELEGRAM_URL = "https://api.telegram.org/"
+
+	MESSAGE_LIMIT = 4096
+	CAPTION_LIMIT = 1024
+)
+
+var markdownEscaper = strings.NewReplacer(
+	"_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)", "~", "\\~", "`", "\\`",
+	">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-", "=", "\\=", "|", "\\|", "{", "\\{", "}", "\\}",
+	".", "\\.", "!", "\\!", "\\", "\\\\",
+)
+
+var urlEscaper = strings.NewReplacer(")", "\\)", "\\", "\\\\")
+
+type TelegramMessage struct {
+	ChatId    string `json:"chat_id"`
+	Text      string `json:"text,omitempty"`
+	Photo     string `json:"photo,omitempty"`
+	Caption   string `json:"caption,omitempty"`
+	ParseMode string `json:"parse_mode"`
+}
+
+func NewTelegramAgent(config *viper.Viper, logger *zerolog.Logger, restyClient *resty.Client) *TelegramAgent {
+
+	if config.GetString("botToken") == "" {
+		logger.Error().Msg("Missing telegram bot token configuration.")
+		return nil
+	}
+
+	if config.GetString("chatId") == "" {
+		logger.Error().Msg("Missing telegram chat id configuration.")
+		return nil
+	}
+
+	url := TELEGRAM_URL
+	if config.GetString("url") != "" {
+		url = strings.TrimSuffix(config.GetString("url"), "/") + "/"
+	}
+
+	events := []notification.EventType{}
+	_ = config.UnmarshalKey("events", &events)
+
+	return &TelegramAgent{
+		WebhookAgent: *notification.NewWebhookAgent(url+"bot"+config.GetString("botToken")+"/", events,
+			logger.With().Str("Component", "TelegramAgent").Logger(), restyClient),
+		chatId: config.GetString("chatId"),
+	}
+}
+
+type TelegramAgent struct {
+	notification.WebhookAgent
+	chatId string
+}
+
+func (g *TelegramAgent) Name() string {
+	return "telegram"
+}
+
+// escape escapes the text for the MarkdownV2 parse mode.
+func escape(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+func link(text string, url string) string {
+	return fmt.Sprintf("[%s](%s)", escape(text), urlEscaper.Replace(url))
+}
+
+// getMovieText builds the MarkdownV2 text of the movie, the overview is cut
+// so the visible text fits in the limit.
+func (g *TelegramAgent) getMovieText(header string, movie *radarr.Movie, item *provider.ListItem, limit int) string {
+	title := fmt.Sprintf("%s (%d)", movie.Title, movie.Year)
+	tmdbUrl := fmt.Sprintf("https://www.themoviedb.org/movie/%d", movie.TmdbID)
+	ratings := ""
+	if item != nil {
+		ratings = fmt.Sprintf("IMDb %.1f/10 | Metacritic %d/100 | Rotten Tomatoes %d%%",
+			item.Ratings.Imdb, item.Ratings.Metacritic, item.Ratings.RottenTomatoes)
+	}
+
+	links := link("TMDb", tmdbUrl)
+	visibleLinks := "TMDb"
+	titleUrl := tmdbUrl
+	if movie.ImdbId != "" {
+		titleUrl = fmt.Sprintf("https://www.imdb.com/title/%s", movie.ImdbId)
+		links = link("IMDb", titleUrl) + " \\| " + links
+		visibleLinks = "IMDb | " + visibleLinks
+	}
+
+	used := utf8.RuneCountInString(header + title + ratings + visibleLinks + "\n\n\n\n")
+	overview := notification.Truncate(limit-used, movie.Overview)
+
+	text := fmt.Sprintf("%s\n*%s*\n", header, link(title, titleUrl))
+	if ratings != "" {
+		text += escape(ratings) + "\n"
+	}
+	text += links
+	if overview != "" {
+		text += "\n\n" + escape(overview)
+	}
+	return text
+}
+
+func (g *TelegramAgent) getMovieMessage(header string, movie *radarr.Movie, item *provider.ListItem) (string, TelegramMessage) {
+	if poster := movie.GetPoster(); poster != "" {
+		return "sendPhoto", TelegramMessage{
+			ChatId:    g.chatId,
+			Photo:     poster,
+			Caption:   g.getMovieText(header, movie, item, CAPTION_LIMIT),
+			ParseMode: "MarkdownV2",
+		}
+	}
+	return "sendMessage", TelegramMessage{
+		ChatId:    g.chatId,
+		Text:      g.getMovieText(header, movie, item, MESSAGE_LIMIT),
+		ParseMode: "MarkdownV2",
+	}
+}
+
+func (g *TelegramAgent) getTextMessage(text string) (string, TelegramMessage) {
+	return "sendMessage", TelegramMessage{
+		ChatId:    g.chatId,
+		Text:      escape(notification.Truncate(MESSAGE_LIMIT, text)),
+		ParseMode: "MarkdownV2",
+	}
+}
+
+func (g *TelegramAgent) getMessage(event notification.Event) (string, interface{}) {
+	g.WebhookAgent.Logger.Debug().Interface("event", event).Msg("Processing message")
+
+	switch event.Type {
+	case notification.START_FEED:
+		return g.getTextMessage(fmt.Sprintf("Start processing feed %s", event.Data["name"]))
+	case notification.FINISH_FEED:
+		return g.getTextMessage(fmt.Sprintf("Finish processing feed %s, added %d movies", event.Data["name"], event.Data["added"]))
+	case notification.FINISH_ALL_FEEDS:
+		return g.getTextMessage(fmt.Sprintf("Finish processing all feeds, added %d movies", event.Data["added"]))
+	case notification.ADDED_MOVIE, notification.REVISION_MOVIE:
+		// the events read from the spool may miss the movie or the item
+		movie, ok := event.Data["movie"].(*radarr.Movie)
+		if !ok || movie == nil {
+			g.WebhookAgent.Logger.Warn().Interface("event", event).Msg("Skipping event without movie")
+			return "", nil
+		}
+		item, _ := event.Data["item"].(*provider.ListItem)
+		if event.Type == notification.ADDED_MOVIE {
+			return g.getMovieMessage(escape(fmt.Sprintf("Added new movie found in feed %s", event.Data["name"])), movie, item)
+		}
+		return g.getMovieMessage(escape(fmt.Sprintf("Movie for revision found in feed %s", event.Data["name"])), movie, item)
+	case notification.LIST_FAILED:
+		return g.getTextMessage(fmt.Sprintf("Failed processing feed %s: %s", event.Data["name"], event.Data["error"]))
+	case notification.ITEM_ERROR:
+		title := ""
+		if item, ok := event.Data["item"].(*provider.ListItem); ok && item != nil {
+			title = fmt.Sprintf(" '%s (%d)'", item.Title, item.Year)
+		}
+		return g.getTextMessage(fmt.Sprintf("Error processing movie%s in feed %s: %s", title, event.Data["name"], event.Data["error"]))
+	case notification.DIGEST:
+		return g.getTextMessage(notification.DigestTitle(event) + "\n\n" + strings.Join(notification.DigestLines(event), "\n"))
+	default:
+		g.WebhookAgent.Logger.Error().Interface("event", event).Msg("Invalid event type")
+		return "", nil
+	}
+}
+
+func (a *TelegramAgent) SendEvent(event notification.Event) error {
+	if a.IsSubscribe(event.Type) {
+		method, message := a.getMessage(event)
+		return a.SendMessageTo(a.Url+method, event, message)
+	}
+	return nil
+}
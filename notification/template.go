@@ -0,0 +1,216 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/services/radarr"
+	"github.com/spf13/viper"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// DefaultTitles and DefaultMessages are plain text, for the agents that
+// send one title and one message by event.
+var DefaultTitles = map[EventType]string{
+	START_FEED:       "Seekerr: {{ .Name }}",
+	FINISH_FEED:      "Seekerr: {{ .Name }}",
+	FINISH_ALL_FEEDS: "Seekerr",
+	ADDED_MOVIE:      "Seekerr: {{ .Name }}",
+	REVISION_MOVIE:   "Seekerr: {{ .Name }}",
+	LIST_FAILED:      "Seekerr: {{ .Name }}",
+	ITEM_ERROR:       "Seekerr: {{ .Name }}",
+	DIGEST:           "{{ .Summary }}",
+}
+
+var DefaultMessages = map[EventType]string{
+	START_FEED:       "Start processing feed {{ .Name }}",
+	FINISH_FEED:      "Finish processing feed {{ .Name }}, added {{ .Added }} movies",
+	FINISH_ALL_FEEDS: "Finish processing all feeds, added {{ .Added }} movies",
+	ADDED_MOVIE: "Added new movie '{{ .Movie.Title }} ({{ .Movie.Year }})', " +
+		"ratings: imdb {{ printf \"%.1f\" .Item.Ratings.Imdb }}/10, metacritic {{ .Item.Ratings.Metacritic }}/100, " +
+		"rotten tomatoes {{ .Item.Ratings.RottenTomatoes }}%",
+	REVISION_MOVIE: "Movie '{{ .Movie.Title }} ({{ .Movie.Year }})' for revision, " +
+		"ratings: imdb {{ printf \"%.1f\" .Item.Ratings.Imdb }}/10, metacritic {{ .Item.Ratings.Metacritic }}/100, " +
+		"rotten tomatoes {{ .Item.Ratings.RottenTomatoes }}%",
+	LIST_FAILED: "Failed processing feed {{ .Name }}: {{ .Error }}",
+	ITEM_ERROR:  "Error processing movie '{{ .Item.Title }} ({{ .Item.Year }})': {{ .Error }}",
+	DIGEST:      "{{ join .Lines \"\\n\" }}",
+}
+
+// MergeMessages returns a copy of the defaults with the messages of the
+// agent, for the agents that only change some of the events.
+func MergeMessages(defaults map[EventType]string, messages map[EventType]string) map[EventType]string {
+	result := make(map[EventType]string, len(defaults))
+	for eventType, message := range defaults {
+		result[eventType] = message
+	}
+	for eventType, message := range messages {
+		result[eventType] = message
+	}
+	return result
+}
+
+// TemplateData is what the message templates can use, the fields that don't
+// apply to the event are empty.
+type TemplateData struct {
+	Event    Event
+	Name     string
+	Item     *provider.ListItem
+	Movie    *radarr.Movie
+	Approved int
+	Added    int
+	Error    string
+	Url      string
+	ImdbUrl  string
+	TmdbUrl  string
+	Poster   string
+	Summary  string
+	Lines    []string
+}
+
+func NewTemplateData(event Event) TemplateData {
+	data := TemplateData{Event: event}
+	data.Name, _ = event.Data["name"].(string)
+	data.Item, _ = event.Data["item"].(*provider.ListItem)
+	data.Movie, _ = event.Data["movie"].(*radarr.Movie)
+	data.Approved, _ = event.Data["approved"].(int)
+	data.Added, _ = event.Data["added"].(int)
+	data.Error, _ = event.Data["error"].(string)
+
+	if data.Movie != nil {
+		data.TmdbUrl = fmt.Sprintf("https://www.themoviedb.org/movie/%d", data.Movie.TmdbID)
+		if data.Movie.TitleSlug != "" {
+			data.TmdbUrl += "-" + data.Movie.TitleSlug
+		}
+		data.Url = data.TmdbUrl
+		if data.Movie.ImdbId != "" {
+			data.ImdbUrl = fmt.Sprintf("https://www.imdb.com/title/%s", data.Movie.ImdbId)
+			data.Url = data.ImdbUrl
+		}
+		data.Poster = data.Movie.GetPoster()
+	}
+
+	if event.Type == DIGEST {
+		data.Summary = DigestTitle(event)
+		data.Lines = DigestLines(event)
+	}
+	return data
+}
+
+// Truncate limits the text to max characters, ending with an ellipsis when cut.
+func Truncate(max int, text string) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	if max <= 1 {
+		return ""
+	}
+	return strings.TrimSpace(string([]rune(text)[:max-1])) + "…"
+}
+
+var templateFuncs = template.FuncMap{
+	"truncate": Truncate,
+	"join":     strings.Join,
+}
+
+// MessageTemplates renders the text of each event type, the templates in the
+// config replace the defaults of the agent.
+type MessageTemplates struct {
+	defaults  map[EventType]*template.Template
+	templates map[EventType]*template.Template
+}
+
+func parseTemplates(name string, sources map[EventType]string) (map[EventType]*template.Template, error) {
+	templates := map[EventType]*template.Template{}
+	for eventType, source := range sources {
+		parsed, err := template.New(name + ":" + string(eventType)).Funcs(templateFuncs).Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template for %s: %w", name, eventType, err)
+		}
+		templates[eventType] = parsed
+	}
+	return templates, nil
+}
+
+// NewMessageTemplates reads the templates of the key in the config, by event
+// type, the config may be nil to use only the defaults.
+func NewMessageTemplates(config *viper.Viper, key string, defaults map[EventType]string) (*MessageTemplates, error) {
+	result := &MessageTemplates{}
+
+	var err error
+	if result.defaults, err = parseTemplates(key, defaults); err != nil {
+		return nil, err
+	}
+
+	sources := map[EventType]string{}
+	if config != nil {
+		// viper lower cases the keys
+		for eventType, source := range config.GetStringMapString(key) {
+			sources[EventType(strings.ToUpper(eventType))] = source
+		}
+	}
+	if result.templates, err = parseTemplates(key, sources); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func execute(tmpl *template.Template, data TemplateData) (string, error) {
+	result := bytes.Buffer{}
+	if err := tmpl.Execute(&result, data); err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// Render returns the text of the event, when the configured template fails
+// the default text is returned with the error.
+func (m *MessageTemplates) Render(event Event) (string, error) {
+	data := NewTemplateData(event)
+
+	var err error
+	if tmpl, ok := m.templates[event.Type]; ok {
+		var text string
+		if text, err = execute(tmpl, data); err == nil {
+			return text, nil
+		}
+	}
+
+	if tmpl, ok := m.defaults[event.Type]; ok {
+		text, defaultErr := execute(tmpl, data)
+		if err == nil {
+			err = defaultErr
+		}
+		return text, err
+	}
+
+	if err == nil {
+		err = fmt.Errorf("no template for event %s", event.Type)
+	}
+	return "", err
+}
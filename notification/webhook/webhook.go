@@ -0,0 +1,119 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/notification"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+func NewWebhookAgent(name string, config *viper.Viper, logger *zerolog.Logger, restyClient *resty.Client) *WebhookAgent {
+
+	agentLogger := logger.With().Str("Component", "WebhookAgent").Str("webhook", name).Logger()
+
+	url, err := url.Parse(config.GetString("url"))
+	if err != nil || config.GetString("url") == "" {
+		agentLogger.Error().Err(err).Msg("Invalid webhook url configuration.")
+		return nil
+	}
+
+	var body *template.Template
+	if config.GetString("template") != "" {
+		body, err = template.New(name).Funcs(template.FuncMap{
+			"json": toJson,
+		}).Parse(config.GetString("template"))
+		if err != nil {
+			agentLogger.Error().Err(err).Msg("Invalid webhook template configuration.")
+			return nil
+		}
+	}
+
+	events := []notification.EventType{}
+	_ = config.UnmarshalKey("events", &events)
+
+	agent := &WebhookAgent{
+		WebhookAgent: *notification.NewWebhookAgent(url.String(), events, agentLogger, restyClient),
+		name:         name,
+		template:     body,
+	}
+
+	if config.GetString("method") != "" {
+		agent.Method = strings.ToUpper(config.GetString("method"))
+	}
+	for header, value := range config.GetStringMapString("headers") {
+		agent.Headers[header] = value
+	}
+
+	return agent
+}
+
+// WebhookAgent sends the events to any url, as json or rendered with a template.
+type WebhookAgent struct {
+	notification.WebhookAgent
+	name     string
+	template *template.Template
+}
+
+func (a *WebhookAgent) Name() string {
+	return "webhook:" + a.name
+}
+
+func toJson(value interface{}) (string, error) {
+	result := bytes.Buffer{}
+	encoder := json.NewEncoder(&result)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(value)
+	return strings.TrimSuffix(result.String(), "\n"), err
+}
+
+func (a *WebhookAgent) getMessage(event notification.Event) (interface{}, error) {
+	if a.template == nil {
+		return event, nil
+	}
+
+	message := bytes.Buffer{}
+	if err := a.template.Execute(&message, event); err != nil {
+		return nil, err
+	}
+	return message.String(), nil
+}
+
+func (a *WebhookAgent) SendEvent(event notification.Event) error {
+	if a.IsSubscribe(event.Type) {
+		message, err := a.getMessage(event)
+		if err != nil {
+			// the same template fails on every retry
+			return notification.Permanent(fmt.Errorf("rendering webhook template: %w", err))
+		}
+		return a.SendMessage(event, message)
+	}
+	return nil
+}
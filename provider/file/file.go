@@ -0,0 +1,213 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	FILE_SCHEME   = "file://"
+	DEFAULT_LIMIT = 1000
+	// the processed lines are commented with this prefix
+	DONE_PREFIX = "# done: "
+)
+
+var (
+	imdbRegex  = regexp.MustCompile(`^(?:.*/title/)?(tt\d+)/?(?:[?#].*)?$`)
+	tmdbRegex  = regexp.MustCompile(`^(?:tmdb:\s*|.*themoviedb\.org/movie/)(\d+)`)
+	titleRegex = regexp.MustCompile(`^(.+?)\s*\((\d{4})\)$`)
+)
+
+func NewProvider(logger *zerolog.Logger) *Provider {
+	return &Provider{
+		logger: logger.With().Str("Component", "File Provider").Logger(),
+	}
+}
+
+// Provider reads the movies of a text file, or of all the files of a
+// directory, one per line with the imdb id, the tmdb id as tmdb:603, the
+// imdb or tmdb url, or the title with the year like "The Matrix (1999)".
+// The lines starting with # are comments.
+type Provider struct {
+	logger zerolog.Logger
+}
+
+func (p *Provider) GetItems(config provider.ListConfig) ([]provider.ListItem, error) {
+
+	limit := config.Filter.Limit
+	if limit == 0 {
+		limit = DEFAULT_LIMIT
+	}
+	result := []provider.ListItem{}
+
+	files, err := Files(config.Url)
+	if err != nil {
+		p.logger.Error().Err(err).Msg("Reading files")
+		return result, fmt.Errorf("reading file list %s: %w", config.Url, err)
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			p.logger.Error().Err(err).Str("file", file).Msg("Reading file")
+			return result, fmt.Errorf("reading file list %s: %w", file, err)
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() && len(result) < limit {
+			if item, ok := ParseLine(scanner.Text()); ok {
+				result = append(result, item)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// MarkDone comments the lines of the processed items, so they aren't
+// processed again. The files are read again, so the lines added since the
+// items were fetched are kept.
+func (p *Provider) MarkDone(config provider.ListConfig, items []provider.ListItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	files, err := Files(config.Url)
+	if err != nil {
+		return fmt.Errorf("reading file list %s: %w", config.Url, err)
+	}
+
+	for _, file := range files {
+		if err := p.markFile(file, items); err != nil {
+			return fmt.Errorf("marking file list %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+func (p *Provider) markFile(file string, items []provider.ListItem) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	marked := 0
+	for index, line := range lines {
+		item, ok := ParseLine(line)
+		if !ok || !contains(items, item) {
+			continue
+		}
+		lines[index] = DONE_PREFIX + strings.TrimLeft(line, " \t")
+		marked++
+	}
+	if marked == 0 {
+		return nil
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	// replace the file at once, the synced folders may be read meanwhile
+	temp := filepath.Join(filepath.Dir(file), "."+filepath.Base(file)+".seekerr")
+	if err := os.WriteFile(temp, []byte(strings.Join(lines, "")), info.Mode()); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, file); err != nil {
+		_ = os.Remove(temp)
+		return err
+	}
+
+	p.logger.Info().Str("file", file).Int("Count", marked).Msg("Marked done lines.")
+	return nil
+}
+
+// ParseLine returns the item of the line, false for the empty lines and
+// comments.
+func ParseLine(line string) (provider.ListItem, bool) {
+	item := provider.ListItem{}
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return item, false
+	}
+
+	if match := imdbRegex.FindStringSubmatch(line); match != nil {
+		item.Imdb = match[1]
+	} else if match := tmdbRegex.FindStringSubmatch(line); match != nil {
+		item.Tmdb, _ = strconv.Atoi(match[1])
+	} else if match := titleRegex.FindStringSubmatch(line); match != nil {
+		item.Title = match[1]
+		item.Year, _ = strconv.Atoi(match[2])
+	} else {
+		item.Title = line
+	}
+	return item, true
+}
+
+// Files returns the path of the list, or the files of the directory sorted
+// by name, without the hidden files like the temporary files of the sync
+// tools.
+func Files(url string) ([]string, error) {
+	path := strings.TrimPrefix(url, FILE_SCHEME)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") && !strings.HasSuffix(entry.Name(), "~") {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func contains(items []provider.ListItem, item provider.ListItem) bool {
+	for _, current := range items {
+		if current.Imdb == item.Imdb && current.Tmdb == item.Tmdb && current.Title == item.Title && current.Year == item.Year {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package file
+
+import (
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected provider.ListItem
+		ok       bool
+	}{
+		{line: "tt0133093", expected: provider.ListItem{Imdb: "tt0133093"}, ok: true},
+		{line: "  tt0133093  ", expected: provider.ListItem{Imdb: "tt0133093"}, ok: true},
+		{line: "https://www.imdb.com/title/tt0111161/", expected: provider.ListItem{Imdb: "tt0111161"}, ok: true},
+		{line: "https://www.imdb.com/title/tt0111161/?ref_=nv_sr_1", expected: provider.ListItem{Imdb: "tt0111161"}, ok: true},
+		{line: "https://m.imdb.com/title/tt0111161#reviews", expected: provider.ListItem{Imdb: "tt0111161"}, ok: true},
+		{line: "tmdb:603", expected: provider.ListItem{Tmdb: 603}, ok: true},
+		{line: "tmdb: 603", expected: provider.ListItem{Tmdb: 603}, ok: true},
+		{line: "https://www.themoviedb.org/movie/603-the-matrix?language=en", expected: provider.ListItem{Tmdb: 603}, ok: true},
+		{line: "The Matrix (1999)", expected: provider.ListItem{Title: "The Matrix", Year: 1999}, ok: true},
+		{line: "Blade Runner 2049 (2017)", expected: provider.ListItem{Title: "Blade Runner 2049", Year: 2017}, ok: true},
+		{line: "The Matrix", expected: provider.ListItem{Title: "The Matrix"}, ok: true},
+		{line: "# tt0133093"},
+		{line: DONE_PREFIX + "tt0133093"},
+		{line: ""},
+		{line: " \t\r\n"},
+	}
+
+	for _, test := range tests {
+		item, ok := ParseLine(test.line)
+		if ok != test.ok || item.Imdb != test.expected.Imdb || item.Tmdb != test.expected.Tmdb ||
+			item.Title != test.expected.Title || item.Year != test.expected.Year {
+			t.Errorf("ParseLine(%q) = %+v %t, expected %+v %t", test.line, item, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestMarkFile(t *testing.T) {
+	content := "# my movies\r\n" +
+		"tt0133093\r\n" +
+		"\r\n" +
+		"  https://www.imdb.com/title/tt0111161/?ref_=nv_sr_1\r\n" +
+		"tmdb:603\n" +
+		"The Matrix (1999)\n" +
+		"# done: tt0068646\n" +
+		"Amélie (2001)"
+	expected := "# my movies\r\n" +
+		"# done: tt0133093\r\n" +
+		"\r\n" +
+		"# done: https://www.imdb.com/title/tt0111161/?ref_=nv_sr_1\r\n" +
+		"tmdb:603\n" +
+		"The Matrix (1999)\n" +
+		"# done: tt0068646\n" +
+		"# done: Amélie (2001)"
+
+	file := filepath.Join(t.TempDir(), "movies.txt")
+	if err := os.WriteFile(file, []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := zerolog.Nop()
+	items := []provider.ListItem{{Imdb: "tt0133093"}, {Imdb: "tt0111161"}, {Title: "Amélie", Year: 2001}}
+	if err := NewProvider(&logger).markFile(file, items); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != expected {
+		t.Errorf("markFile wrote %q, expected %q", result, expected)
+	}
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("markFile changed the mode of the file to %v", info.Mode())
+	}
+	if files, _ := os.ReadDir(filepath.Dir(file)); len(files) != 1 {
+		t.Errorf("markFile left %d files, expected only the list", len(files))
+	}
+}
+
+func TestMarkFileUnchanged(t *testing.T) {
+	content := "tt0133093\ntmdb:603\n"
+	file := filepath.Join(t.TempDir(), "movies.txt")
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := zerolog.Nop()
+	if err := NewProvider(&logger).markFile(file, []provider.ListItem{{Imdb: "tt0111161"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, _ := os.ReadFile(file); string(result) != content {
+		t.Errorf("markFile wrote %q, expected %q", result, content)
+	}
+}
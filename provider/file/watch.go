@@ -0,0 +1,178 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package file
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DEFAULT_WATCH_DELAY groups the changes of the files, the editors and sync
+// tools usually write them in several steps.
+const DEFAULT_WATCH_DELAY = 10 * time.Second
+
+type watchedList struct {
+	url string
+	// the file of the list, empty when the list is a directory
+	file  string
+	timer *time.Timer
+}
+
+func NewWatcher(logger *zerolog.Logger) (*Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{
+		logger:  logger.With().Str("Component", "File Watcher").Logger(),
+		watcher: watcher,
+		delay:   DEFAULT_WATCH_DELAY,
+		lists:   map[string]map[string]*watchedList{},
+	}, nil
+}
+
+// Watcher calls back with the name of the file lists changed with pending
+// lines.
+type Watcher struct {
+	logger  zerolog.Logger
+	watcher *fsnotify.Watcher
+	delay   time.Duration
+	// the lists by directory and name
+	lists map[string]map[string]*watchedList
+	mutex sync.Mutex
+}
+
+// Add watches the files of the list, the directory of the file is watched
+// since the files are usually replaced when saved.
+func (w *Watcher) Add(listName string, url string) error {
+	path, err := filepath.Abs(strings.TrimPrefix(url, FILE_SCHEME))
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	list := &watchedList{url: url}
+	dir := path
+	if !info.IsDir() {
+		list.file = path
+		dir = filepath.Dir(path)
+	}
+
+	if _, ok := w.lists[dir]; !ok {
+		if err := w.watcher.Add(dir); err != nil {
+			return err
+		}
+		w.lists[dir] = map[string]*watchedList{}
+	}
+	w.lists[dir][listName] = list
+	w.logger.Info().Str("path", path).Msgf("Watching list '%s'.", listName)
+	return nil
+}
+
+// Run calls back the changed lists until the watcher is closed.
+func (w *Watcher) Run(callback func(listName string)) {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.changed(event, callback)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Error().Err(err).Msg("Watching lists")
+		}
+	}
+}
+
+func (w *Watcher) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, lists := range w.lists {
+		for _, list := range lists {
+			if list.timer != nil {
+				list.timer.Stop()
+			}
+		}
+	}
+	return w.watcher.Close()
+}
+
+func (w *Watcher) changed(event fsnotify.Event, callback func(listName string)) {
+	name := filepath.Base(event.Name)
+	// the temporary files of the sync tools and of the done lines
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") || event.Op == fsnotify.Chmod {
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for listName, list := range w.lists[filepath.Dir(event.Name)] {
+		if list.file != "" && list.file != event.Name {
+			continue
+		}
+		if list.timer != nil {
+			list.timer.Stop()
+		}
+		listName, url := listName, list.url
+		list.timer = time.AfterFunc(w.delay, func() {
+			// the lists with every line done, like after marking them
+			if !pending(url) {
+				w.logger.Debug().Msgf("Skipping list '%s' without pending lines.", listName)
+				return
+			}
+			w.logger.Info().Str("file", event.Name).Msgf("Changed list '%s'.", listName)
+			callback(listName)
+		})
+	}
+}
+
+// pending is true when the files of the list have lines not done.
+func pending(url string) bool {
+	files, err := Files(url)
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if _, ok := ParseLine(line); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package generic
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/rs/zerolog"
+	"strings"
+)
+
+func NewCsvProvider(logger *zerolog.Logger, restyClient *resty.Client) *CsvProvider {
+	return &CsvProvider{
+		restyClient: restyClient,
+		logger:      logger.With().Str("Component", "CSV Provider").Logger(),
+	}
+}
+
+// CsvProvider reads the items of a csv with a header, the fields are the
+// names of the columns.
+type CsvProvider struct {
+	logger      zerolog.Logger
+	restyClient *resty.Client
+}
+
+func (p *CsvProvider) GetItems(config provider.ListConfig) ([]provider.ListItem, error) {
+
+	result := []provider.ListItem{}
+
+	body, err := fetch(p.restyClient, "csv", config.Url)
+	if err != nil {
+		p.logger.Error().Err(err).Msg("Fetching csv")
+		return result, fmt.Errorf("fetching csv list %s: %w", config.Url, err)
+	}
+	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	// the spreadsheets of some locales export with semicolons
+	if header, _, _ := bytes.Cut(body, []byte("\n")); bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		p.logger.Error().Err(err).Msg("Parsing csv")
+		return result, fmt.Errorf("parsing csv list %s: %w", config.Url, err)
+	}
+	if len(records) == 0 {
+		return result, fmt.Errorf("parsing csv list %s: missing header", config.Url)
+	}
+
+	columns := map[string]int{}
+	for index, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+	column := func(record []string, name string) string {
+		index, ok := columns[strings.ToLower(name)]
+		if !ok || index >= len(record) {
+			return ""
+		}
+		return record[index]
+	}
+
+	fields := config.Fields
+	found := false
+	for _, name := range []string{field(fields.Title, "title"), field(fields.Imdb, "imdb"), field(fields.Tmdb, "tmdb")} {
+		_, ok := columns[strings.ToLower(name)]
+		found = found || ok
+	}
+	if !found {
+		return result, fmt.Errorf("parsing csv list %s: missing the title, imdb or tmdb columns", config.Url)
+	}
+
+	for _, record := range records[1:] {
+		item, ok := newItem(
+			column(record, field(fields.Title, "title")),
+			column(record, field(fields.Year, "year")),
+			column(record, field(fields.Imdb, "imdb")),
+			column(record, field(fields.Tmdb, "tmdb")),
+		)
+		if !ok {
+			continue
+		}
+		p.logger.Debug().Interface("item", item).Msgf("Processing csv list item %s.", item.Title)
+
+		result = append(result, item)
+		if len(result) >= limit(config) {
+			break
+		}
+	}
+
+	return result, nil
+}
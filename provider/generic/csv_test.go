@@ -0,0 +1,99 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package generic
+
+import (
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCsvGetItems(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		fields   provider.ListFields
+		expected []provider.ListItem
+	}{
+		{name: "imdb export",
+			content: "\xef\xbb\xbfPosition,Const,Created,Title,Year\n" +
+				"1,tt0133093,2023-01-01,The Matrix,1999\n" +
+				"2,tt0111161,2023-01-02,\"Shawshank Redemption, The\",1994\n" +
+				"3,tt0068646,2023-01-03,\"The \"\"Godfather\"\"\",1972\n",
+			fields: provider.ListFields{Title: "Title", Year: "Year", Imdb: "Const"},
+			expected: []provider.ListItem{
+				{Title: "The Matrix", Year: 1999, Imdb: "tt0133093"},
+				{Title: "Shawshank Redemption, The", Year: 1994, Imdb: "tt0111161"},
+				{Title: "The \"Godfather\"", Year: 1972, Imdb: "tt0068646"},
+			}},
+		{name: "default columns in other case",
+			content: "TITLE,YEAR,TMDB\n" +
+				"Dune,2021-09-15,https://www.themoviedb.org/movie/438631-dune\n" +
+				",,\n" +
+				"Amélie,2001\n",
+			expected: []provider.ListItem{
+				{Title: "Dune", Year: 2021, Tmdb: 438631},
+				{Title: "Amélie", Year: 2001},
+			}},
+		{name: "semicolons",
+			content: "name;date;imdb\n" +
+				"\"Heat; director's cut\";1995;https://www.imdb.com/title/tt0113277/\n",
+			fields: provider.ListFields{Title: "name", Year: "date"},
+			expected: []provider.ListItem{
+				{Title: "Heat; director's cut", Year: 1995, Imdb: "tt0113277"},
+			}},
+	}
+
+	logger := zerolog.Nop()
+	csvProvider := NewCsvProvider(&logger, nil)
+	for _, test := range tests {
+		file := filepath.Join(t.TempDir(), "movies.csv")
+		if err := os.WriteFile(file, []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		items, err := csvProvider.GetItems(provider.ListConfig{Url: FILE_SCHEME + file, Fields: test.fields})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(items, test.expected) {
+			t.Errorf("%s: GetItems = %+v, expected %+v", test.name, items, test.expected)
+		}
+	}
+}
+
+func TestCsvGetItemsError(t *testing.T) {
+	logger := zerolog.Nop()
+	csvProvider := NewCsvProvider(&logger, nil)
+	for _, content := range []string{"", "name,date\nThe Matrix,1999\n"} {
+		file := filepath.Join(t.TempDir(), "movies.csv")
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := csvProvider.GetItems(provider.ListConfig{Url: FILE_SCHEME + file}); err == nil {
+			t.Errorf("GetItems(%q) expected error", content)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package generic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/utils/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	FILE_SCHEME   = "file://"
+	DEFAULT_LIMIT = 1000
+)
+
+var (
+	imdbRegex = regexp.MustCompile(`tt\d+`)
+	yearRegex = regexp.MustCompile(`\d{4}`)
+	// the tmdb urls, like https://www.themoviedb.org/movie/603-the-matrix
+	tmdbRegex = regexp.MustCompile(`^(?:.*/movie/)?(\d+)`)
+)
+
+// fetch reads the list from the url or from the local file of a file:// url.
+func fetch(restyClient *resty.Client, service string, url string) ([]byte, error) {
+	if strings.HasPrefix(url, FILE_SCHEME) {
+		return os.ReadFile(strings.TrimPrefix(url, FILE_SCHEME))
+	}
+
+	resp, err := http.ServiceRequest(restyClient, service).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, errors.New(resp.Status())
+	}
+	return resp.Body(), nil
+}
+
+func limit(config provider.ListConfig) int {
+	if config.Filter.Limit == 0 {
+		return DEFAULT_LIMIT
+	}
+	return config.Filter.Limit
+}
+
+func field(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func toString(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(value)
+	case json.Number:
+		return value.String()
+	case []interface{}:
+		// the first value of the fields with [*]
+		for _, element := range value {
+			if text := toString(element); text != "" {
+				return text
+			}
+		}
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// newItem creates the item with the values of the mapped fields, false when
+// it has no title or ids.
+func newItem(title string, year string, imdb string, tmdb string) (provider.ListItem, bool) {
+	item := provider.ListItem{
+		Title: strings.TrimSpace(title),
+		Imdb:  imdbRegex.FindString(imdb),
+	}
+	// the year can be a date, like 2021-09-15
+	item.Year, _ = strconv.Atoi(yearRegex.FindString(year))
+	if match := tmdbRegex.FindStringSubmatch(strings.TrimSpace(tmdb)); match != nil {
+		item.Tmdb, _ = strconv.Atoi(match[1])
+	}
+	return item, item.Title != "" || item.Imdb != "" || item.Tmdb != 0
+}
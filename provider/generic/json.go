@@ -0,0 +1,146 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package generic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/rs/zerolog"
+	"regexp"
+	"strconv"
+)
+
+// the segments of a path, like the movies, 0 and title of $.movies[0].title
+var segmentRegex = regexp.MustCompile(`[^.\[\]$]+|\[(\d+|\*)\]`)
+
+func NewJsonProvider(logger *zerolog.Logger, restyClient *resty.Client) *JsonProvider {
+	return &JsonProvider{
+		restyClient: restyClient,
+		logger:      logger.With().Str("Component", "JSON Provider").Logger(),
+	}
+}
+
+// JsonProvider reads the items of a json document, the items path points to
+// the array of items and the field paths are relative to each item.
+type JsonProvider struct {
+	logger      zerolog.Logger
+	restyClient *resty.Client
+}
+
+// lookup returns the value of the path, a subset of JSONPath with the keys
+// and indexes of the objects and arrays, like $.data.movies[*] or ids.imdb.
+// The rest of the path after [*] is looked up in each element, like in
+// $.lists[*].movies[*] or items[*].ids.imdb, and the arrays found are joined.
+func lookup(value interface{}, path string) interface{} {
+	return lookupSegments(value, segmentRegex.FindAllStringSubmatch(path, -1))
+}
+
+func lookupSegments(value interface{}, segments [][]string) interface{} {
+	for position, segment := range segments {
+		switch {
+		case segment[1] == "*":
+			array, ok := value.([]interface{})
+			if !ok {
+				return nil
+			}
+			if position == len(segments)-1 {
+				return array
+			}
+			result := []interface{}{}
+			for _, element := range array {
+				switch found := lookupSegments(element, segments[position+1:]).(type) {
+				case nil:
+				case []interface{}:
+					result = append(result, found...)
+				default:
+					result = append(result, found)
+				}
+			}
+			return result
+		case segment[1] != "":
+			index, _ := strconv.Atoi(segment[1])
+			array, ok := value.([]interface{})
+			if !ok || index >= len(array) {
+				return nil
+			}
+			value = array[index]
+		default:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			value = object[segment[0]]
+		}
+	}
+	return value
+}
+
+func (p *JsonProvider) GetItems(config provider.ListConfig) ([]provider.ListItem, error) {
+
+	result := []provider.ListItem{}
+
+	body, err := fetch(p.restyClient, "json", config.Url)
+	if err != nil {
+		p.logger.Error().Err(err).Msg("Fetching json")
+		return result, fmt.Errorf("fetching json list %s: %w", config.Url, err)
+	}
+
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// keep the ids as they are
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		p.logger.Error().Err(err).Msg("Parsing json")
+		return result, fmt.Errorf("parsing json list %s: %w", config.Url, err)
+	}
+
+	items, ok := lookup(document, config.Fields.Items).([]interface{})
+	if !ok {
+		return result, fmt.Errorf("parsing json list %s: no array of items in '%s'", config.Url, field(config.Fields.Items, "$"))
+	}
+
+	fields := config.Fields
+	for _, value := range items {
+		item, ok := newItem(
+			toString(lookup(value, field(fields.Title, "title"))),
+			toString(lookup(value, field(fields.Year, "year"))),
+			toString(lookup(value, field(fields.Imdb, "imdb"))),
+			toString(lookup(value, field(fields.Tmdb, "tmdb"))),
+		)
+		if !ok {
+			p.logger.Warn().Interface("item", value).Msg("Skipping json item without title or ids.")
+			continue
+		}
+		p.logger.Debug().Interface("item", item).Msgf("Processing json list item %s.", item.Title)
+
+		result = append(result, item)
+		if len(result) >= limit(config) {
+			break
+		}
+	}
+
+	return result, nil
+}
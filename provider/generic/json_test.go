@@ -0,0 +1,127 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package generic
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const document = `{
+	"data": {
+		"movies": [
+			{"name": "The Matrix", "ids": {"imdb": "tt0133093", "tmdb": 603}},
+			{"name": "Dune", "ids": {"imdb": "tt1160419"}}
+		]
+	},
+	"lists": [
+		{"movies": [{"name": "Amélie"}, {"name": "Heat"}]},
+		{"movies": []},
+		{"other": true},
+		{"movies": [{"name": "Alien"}]}
+	],
+	"sources": [{"ids": [{"imdb": ""}, {"imdb": "tt0078748"}]}]
+}`
+
+func decode(t *testing.T, value string) interface{} {
+	var result interface{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestLookup(t *testing.T) {
+	root := decode(t, document)
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "$.data.movies[0].name", expected: `"The Matrix"`},
+		{path: "data.movies[1].ids.imdb", expected: `"tt1160419"`},
+		{path: "$.data.movies[0].ids.tmdb", expected: `603`},
+		{path: "$.data.movies[2].name", expected: `null`},
+		{path: "$.data.movies[*]", expected: `[{"ids":{"imdb":"tt0133093","tmdb":603},"name":"The Matrix"},` +
+			`{"ids":{"imdb":"tt1160419"},"name":"Dune"}]`},
+		{path: "$.data.movies[*].ids.imdb", expected: `["tt0133093","tt1160419"]`},
+		{path: "$.data.movies[*].ids.tmdb", expected: `[603]`},
+		{path: "$.lists[*].movies[*].name", expected: `["Amélie","Heat","Alien"]`},
+		{path: "$.lists[*].movies", expected: `[{"name":"Amélie"},{"name":"Heat"},{"name":"Alien"}]`},
+		{path: "$.data[*]", expected: `null`},
+		{path: "$.data.movies.name", expected: `null`},
+		{path: "$.missing.movies", expected: `null`},
+	}
+
+	for _, test := range tests {
+		result, err := json.Marshal(lookup(root, test.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result) != test.expected {
+			t.Errorf("lookup(%s) = %s, expected %s", test.path, result, test.expected)
+		}
+	}
+}
+
+func TestJsonGetItems(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "movies.json")
+	if err := os.WriteFile(file, []byte(document), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		fields   provider.ListFields
+		expected []provider.ListItem
+	}{
+		{fields: provider.ListFields{Items: "$.data.movies[*]", Title: "name", Imdb: "ids.imdb", Tmdb: "ids.tmdb"},
+			expected: []provider.ListItem{{Title: "The Matrix", Imdb: "tt0133093", Tmdb: 603}, {Title: "Dune", Imdb: "tt1160419"}}},
+		{fields: provider.ListFields{Items: "$.lists[*].movies[*]", Title: "name"},
+			expected: []provider.ListItem{{Title: "Amélie"}, {Title: "Heat"}, {Title: "Alien"}}},
+		{fields: provider.ListFields{Items: "sources", Imdb: "ids[*].imdb"},
+			expected: []provider.ListItem{{Imdb: "tt0078748"}}},
+	}
+
+	logger := zerolog.Nop()
+	jsonProvider := NewJsonProvider(&logger, nil)
+	for _, test := range tests {
+		items, err := jsonProvider.GetItems(provider.ListConfig{Url: FILE_SCHEME + file, Fields: test.fields})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(items, test.expected) {
+			t.Errorf("GetItems(%+v) = %+v, expected %+v", test.fields, items, test.expected)
+		}
+	}
+
+	if _, err := jsonProvider.GetItems(provider.ListConfig{Url: FILE_SCHEME + file, Fields: provider.ListFields{Items: "data"}}); err == nil {
+		t.Errorf("GetItems expected error for items path without array")
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package imdb
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/utils/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var listPathRegex = regexp.MustCompile(`^/list/(ls\d+)`)
+
+// listParams don't change the items of the list, the lists with other
+// params, like the filters by rating or type, are read from the pages.
+var listParams = map[string]bool{"sort": true, "mode": true, "page": true, "ref_": true, "st_dt": true, "view": true}
+
+// exportUrl returns the url of the csv export of the list, false when the
+// url isn't of a list or its items are filtered or sorted.
+func exportUrl(pageUrl *url.URL) (string, bool) {
+	match := listPathRegex.FindStringSubmatch(pageUrl.Path)
+	if match == nil {
+		return "", false
+	}
+	for param, values := range pageUrl.Query() {
+		if !listParams[param] {
+			return "", false
+		}
+		if param == "sort" && values[0] != "" && values[0] != "list_order,asc" {
+			return "", false
+		}
+	}
+
+	export := url.URL{Scheme: pageUrl.Scheme, Host: pageUrl.Host, Path: "/list/" + match[1] + "/export"}
+	return export.String(), true
+}
+
+// fetchExport returns the items of the list csv export, with the columns
+// Const, Title, Year and Title Type.
+func (p *Provider) fetchExport(exportUrl string) ([]provider.ListItem, error) {
+	resp, err := http.ServiceRequest(p.restyClient, "imdb").Get(exportUrl)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, errors.New(resp.Status())
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(resp.Body(), []byte("\xef\xbb\xbf")))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty export")
+	}
+
+	columns := map[string]int{}
+	for index, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+	if _, ok := columns["const"]; !ok {
+		return nil, fmt.Errorf("missing Const column in %v", records[0])
+	}
+	column := func(record []string, name string) string {
+		if index, ok := columns[name]; ok && index < len(record) {
+			return strings.TrimSpace(record[index])
+		}
+		return ""
+	}
+
+	items := []provider.ListItem{}
+	for _, record := range records[1:] {
+		imdb := idRegex.FindString(column(record, "const"))
+		if imdb == "" || isSeries(column(record, "title type")) {
+			continue
+		}
+		year, _ := strconv.Atoi(column(record, "year"))
+		items = append(items, provider.ListItem{
+			Title: column(record, "title"),
+			Year:  year,
+			Imdb:  imdb,
+		})
+	}
+	return items, nil
+}
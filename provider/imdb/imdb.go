@@ -24,14 +24,30 @@ package imdb
 
 import (
 	"errors"
+	"fmt"
 	"github.com/PuerkitoBio/goquery"
 	"github.com/go-resty/resty/v2"
 	"github.com/lightglitch/seekerr/provider"
+	"github.com/lightglitch/seekerr/utils/http"
 	"github.com/rs/zerolog"
-	"regexp"
-	"strconv"
+	nethttp "net/http"
+	"net/url"
 )
 
+const (
+	DEFAULT_LIMIT = 1000
+	// the lists with more pages are cut
+	MAX_PAGES = 100
+)
+
+// errNoItems is the first page without items, usually a private list or a
+// change of the imdb pages.
+var errNoItems = errors.New("no movies found in the page, the list may be private or the imdb pages changed")
+
+// errLastPage is the missing page after the last one, when the next page
+// isn't linked.
+var errLastPage = errors.New("page not found")
+
 func NewProvider(logger *zerolog.Logger, restyClient *resty.Client) *Provider {
 	return &Provider{
 		restyClient: restyClient,
@@ -48,51 +64,89 @@ func (p *Provider) GetItems(config provider.ListConfig) ([]provider.ListItem, er
 
 	limit := config.Filter.Limit
 	if limit == 0 {
-		limit = 1000
+		limit = DEFAULT_LIMIT
 	}
 	result := []provider.ListItem{}
 
-	resp, err := p.restyClient.R().SetDoNotParseResponse(true).Get(config.Url)
+	pageUrl, err := url.Parse(config.Url)
+	if err != nil {
+		return result, fmt.Errorf("parsing imdb list url %s: %w", config.Url, err)
+	}
+	// the export has every item of the list in one request
+	if export, ok := exportUrl(pageUrl); ok {
+		items, err := p.fetchExport(export)
+		if err == nil && len(items) > 0 {
+			if len(items) > limit {
+				items = items[:limit]
+			}
+			return items, nil
+		}
+		p.logger.Debug().Err(err).Msgf("Can't use the export of the imdb list %s.", config.Url)
+	}
+
+	pageType := getPageType(pageUrl)
+	p.logger.Debug().Str("type", pageType.name).Msgf("Fetching imdb %s.", config.Url)
+
+	seen := map[string]bool{}
+	for number := 1; pageUrl != nil && number <= MAX_PAGES && len(result) < limit; number++ {
+		doc, err := p.fetchPage(pageUrl.String())
+		if errors.Is(err, errLastPage) && number > 1 {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+
+		items, markup := pageType.parse(doc)
+		p.logger.Debug().Str("markup", markup).Int("Count", len(items)).Msgf("Processing imdb page %s.", pageUrl)
+		if len(items) == 0 && number == 1 {
+			return result, fmt.Errorf("parsing imdb list %s: %w", config.Url, errNoItems)
+		}
+
+		added := 0
+		for _, item := range items {
+			key := item.Imdb
+			if key == "" {
+				key = fmt.Sprintf("%s-%d", item.Title, item.Year)
+			}
+			if seen[key] || len(result) >= limit {
+				continue
+			}
+			seen[key] = true
+			result = append(result, item)
+			added++
+		}
+		// the pages after the last one are empty or repeat it
+		if added == 0 {
+			break
+		}
+		pageUrl = pageType.next(pageUrl, doc, len(items))
+	}
+
+	return result, nil
+}
+
+func (p *Provider) fetchPage(pageUrl string) (*goquery.Document, error) {
+	resp, err := http.ServiceRequest(p.restyClient, "imdb").SetDoNotParseResponse(true).Get(pageUrl)
 
 	if err != nil {
 		p.logger.Error().Err(err).Msg("Fetching html")
-		return []provider.ListItem{}, err
+		return nil, fmt.Errorf("fetching imdb list %s: %w", pageUrl, err)
 	}
+	defer resp.RawBody().Close()
 
+	if resp.StatusCode() == nethttp.StatusNotFound {
+		return nil, fmt.Errorf("fetching imdb list %s: %w", pageUrl, errLastPage)
+	}
 	if resp.IsError() {
-		return []provider.ListItem{}, errors.New(resp.Status() + " - " + resp.String())
+		return nil, fmt.Errorf("fetching imdb list %s: %w", pageUrl, errors.New(resp.Status()))
 	}
 
 	// Load the HTML document
 	doc, err := goquery.NewDocumentFromReader(resp.RawBody())
 	if err != nil {
 		p.logger.Error().Err(err).Msg("Parsing html")
-		return result, err
+		return nil, fmt.Errorf("parsing imdb list %s: %w", pageUrl, err)
 	}
-
-	iddRegex := regexp.MustCompile(`tt\d+`)
-	yearRegex := regexp.MustCompile(`\d+`)
-
-	doc.Find("div.lister-list .lister-item").Each(func(index int, s *goquery.Selection) {
-		// For each item found, get the band and title
-		item := s.Find(".lister-item-header a")
-		url := item.AttrOr("href", "")
-		imdbId := ""
-		if url != "" {
-			imdbId = iddRegex.FindString(url)
-		}
-		yearText := yearRegex.FindString(s.Find(".lister-item-header .lister-item-year").Text())
-		year, _ := strconv.Atoi(yearText)
-		p.logger.Debug().Interface("ImdbId", imdbId).Interface("Year", year).Msgf("Processing imdb list item %s.", item.Text())
-
-		if index < limit {
-			result = append(result, provider.ListItem{
-				Title: item.Text(),
-				Year:  year,
-				Imdb:  imdbId,
-			})
-		}
-
-	})
-	return result, nil
+	return doc, nil
 }
@@ -0,0 +1,290 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package imdb
+
+import (
+	"errors"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/provider"
+	"github.com/rs/zerolog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fixtures are the testdata pages by path and page parameter.
+var fixtures = map[string]string{
+	"/list/ls000000001/":        "list_page1.html",
+	"/list/ls000000001/?page=2": "list_page2.html",
+	"/list/ls000000003/export":  "list_export.csv",
+	"/list/ls000000003/":        "list_next_data.html",
+	"/search/title/":            "search_page1.html",
+	"/search/title/?start=3":    "search_page2.html",
+	"/search/title":             "search_next_data.html",
+	"/search/title?start=3":     "search_next_data.html",
+	"/chart/top/":               "chart_top.html",
+	"/chart/moviemeter/":        "chart_moviemeter.html",
+	"/chart/boxoffice/":         "chart_json_ld.html",
+	"/user/ur0000001/watchlist": "watchlist.html",
+	"/user/ur0000002/watchlist": "empty.html",
+}
+
+func newTestServer(requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.RequestURI())
+		key := r.URL.Path
+		if page := r.URL.Query().Get("page"); page != "" {
+			key += "?page=" + page
+		}
+		if start := r.URL.Query().Get("start"); start != "" && start != "1" {
+			key += "?start=" + start
+		}
+		fixture, ok := fixtures[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, "testdata/"+fixture)
+	}))
+}
+
+func TestGetItems(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		limit    int
+		expected []provider.ListItem
+		requests int
+	}{
+		{
+			name: "list with pages",
+			path: "/list/ls000000001/?sort=list_order,asc&mode=detail",
+			expected: []provider.ListItem{
+				{Title: "Dune", Year: 2021, Imdb: "tt1160419"},
+				{Title: "Ant-Man and the Wasp: Quantumania", Year: 2023, Imdb: "tt10954600"},
+				{Title: "Guardians of the Galaxy Vol. 3", Year: 2023, Imdb: "tt6791350"},
+				{Title: "Spider-Man: Across the Spider-Verse", Year: 2023, Imdb: "tt9362722"},
+				{Title: "Barbie", Year: 2023, Imdb: "tt1517268"},
+			},
+			// the export isn't found, the second page doesn't link a next page
+			// and the third isn't found
+			requests: 4,
+		},
+		{
+			name:  "list limited to the first page",
+			path:  "/list/ls000000001/",
+			limit: 2,
+			expected: []provider.ListItem{
+				{Title: "Dune", Year: 2021, Imdb: "tt1160419"},
+				{Title: "Ant-Man and the Wasp: Quantumania", Year: 2023, Imdb: "tt10954600"},
+			},
+			requests: 2,
+		},
+		{
+			name:  "list limited in the second page",
+			path:  "/list/ls000000001/",
+			limit: 4,
+			expected: []provider.ListItem{
+				{Title: "Dune", Year: 2021, Imdb: "tt1160419"},
+				{Title: "Ant-Man and the Wasp: Quantumania", Year: 2023, Imdb: "tt10954600"},
+				{Title: "Guardians of the Galaxy Vol. 3", Year: 2023, Imdb: "tt6791350"},
+				{Title: "Spider-Man: Across the Spider-Verse", Year: 2023, Imdb: "tt9362722"},
+			},
+			requests: 3,
+		},
+		{
+			name:  "list export",
+			path:  "/list/ls000000003/?sort=list_order,asc",
+			limit: 2,
+			expected: []provider.ListItem{
+				{Title: "Dune", Year: 2021, Imdb: "tt1160419"},
+				{Title: "Oppenheimer", Year: 2023, Imdb: "tt15398776"},
+			},
+			requests: 1,
+		},
+		{
+			name: "filtered list next data",
+			path: "/list/ls000000003/?title_type=movie",
+			expected: []provider.ListItem{
+				{Title: "Oppenheimer", Year: 2023, Imdb: "tt15398776"},
+				{Title: "Barbie", Year: 2023, Imdb: "tt1517268"},
+				{Title: "Spider-Man: Across the Spider-Verse", Year: 2023, Imdb: "tt9362722"},
+			},
+			requests: 2,
+		},
+		{
+			name: "search results next data",
+			path: "/search/title?groups=top_100",
+			expected: []provider.ListItem{
+				{Title: "Interstellar", Year: 2014, Imdb: "tt0816692"},
+				{Title: "The Matrix", Year: 1999, Imdb: "tt0133093"},
+			},
+			// the next page repeats the items
+			requests: 2,
+		},
+		{
+			name: "box office chart json-ld",
+			path: "/chart/boxoffice/",
+			expected: []provider.ListItem{
+				{Title: "Spider-Man: Across the Spider-Verse", Year: 0, Imdb: "tt9362722"},
+				{Title: "Amélie", Year: 2001, Imdb: "tt0211915"},
+			},
+			requests: 1,
+		},
+		{
+			name: "search results",
+			path: "/search/title/?genres=sci-fi&title_type=feature",
+			expected: []provider.ListItem{
+				{Title: "Oppenheimer", Year: 2023, Imdb: "tt15398776"},
+				{Title: "Interstellar", Year: 2014, Imdb: "tt0816692"},
+				{Title: "The Matrix", Year: 1999, Imdb: "tt0133093"},
+			},
+			requests: 3,
+		},
+		{
+			name: "top 250 chart",
+			path: "/chart/top/",
+			expected: []provider.ListItem{
+				{Title: "The Shawshank Redemption", Year: 1994, Imdb: "tt0111161"},
+				{Title: "The Godfather", Year: 1972, Imdb: "tt0068646"},
+				{Title: "The Dark Knight", Year: 2008, Imdb: "tt0468569"},
+			},
+			requests: 1,
+		},
+		{
+			name: "most popular chart",
+			path: "/chart/moviemeter/",
+			expected: []provider.ListItem{
+				{Title: "Oppenheimer", Year: 2023, Imdb: "tt15398776"},
+				{Title: "Barbie", Year: 2023, Imdb: "tt1517268"},
+				{Title: "The Matrix", Year: 1999, Imdb: "tt0133093"},
+			},
+			requests: 1,
+		},
+		{
+			name: "watchlist",
+			path: "/user/ur0000001/watchlist",
+			expected: []provider.ListItem{
+				{Title: "Dune", Year: 1984, Imdb: "tt0087182"},
+				{Title: "Amélie", Year: 2001, Imdb: "tt0211915"},
+			},
+			// the second page isn't found
+			requests: 2,
+		},
+	}
+
+	logger := zerolog.Nop()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := []string{}
+			server := newTestServer(&requests)
+			defer server.Close()
+
+			p := NewProvider(&logger, resty.New())
+			config := provider.ListConfig{Url: server.URL + test.path, Filter: provider.ListFilter{Limit: test.limit}}
+			items, err := p.GetItems(config)
+			if err != nil {
+				t.Fatalf("GetItems() error = %v", err)
+			}
+			if !reflect.DeepEqual(items, test.expected) {
+				t.Errorf("GetItems() = %+v, expected %+v", items, test.expected)
+			}
+			if len(requests) != test.requests {
+				t.Errorf("GetItems() requests = %v, expected %d", requests, test.requests)
+			}
+		})
+	}
+}
+
+func TestGetItemsError(t *testing.T) {
+	requests := []string{}
+	server := newTestServer(&requests)
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	p := NewProvider(&logger, resty.New())
+	if _, err := p.GetItems(provider.ListConfig{Url: server.URL + "/list/ls000000002/"}); err == nil {
+		t.Errorf("GetItems() expected error of the missing list")
+	}
+	if _, err := p.GetItems(provider.ListConfig{Url: server.URL + "/user/ur0000002/watchlist"}); !errors.Is(err, errNoItems) {
+		t.Errorf("GetItems() error = %v, expected %v", err, errNoItems)
+	}
+}
+
+func TestExportUrl(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "https://www.imdb.com/list/ls016522954/", expected: "https://www.imdb.com/list/ls016522954/export"},
+		{url: "https://www.imdb.com/list/ls016522954/?sort=list_order,asc&mode=detail&page=1",
+			expected: "https://www.imdb.com/list/ls016522954/export"},
+		{url: "https://www.imdb.com/list/ls016522954/?sort=user_rating,desc", expected: ""},
+		{url: "https://www.imdb.com/list/ls016522954/?title_type=movie&user_rating=6.0%2C", expected: ""},
+		{url: "https://www.imdb.com/chart/top/", expected: ""},
+	}
+
+	for _, test := range tests {
+		current, _ := url.Parse(test.url)
+		result, _ := exportUrl(current)
+		if result != test.expected {
+			t.Errorf("exportUrl(%s) = %s, expected %s", test.url, result, test.expected)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		url      string
+		count    int
+		expected string
+	}{
+		{url: "https://www.imdb.com/list/ls016522954/?sort=list_order,asc", count: 100,
+			expected: "https://www.imdb.com/list/ls016522954/?page=2&sort=list_order%2Casc"},
+		{url: "https://www.imdb.com/list/ls016522954/?page=3", count: 100,
+			expected: "https://www.imdb.com/list/ls016522954/?page=4"},
+		{url: "https://www.imdb.com/search/title/?genres=sci-fi", count: 50,
+			expected: "https://www.imdb.com/search/title/?genres=sci-fi&start=51"},
+		{url: "https://www.imdb.com/search/title/?genres=sci-fi&start=51", count: 50,
+			expected: "https://www.imdb.com/search/title/?genres=sci-fi&start=101"},
+		{url: "https://www.imdb.com/chart/top/", count: 250, expected: ""},
+		{url: "https://www.imdb.com/title/tt0133093/", count: 1, expected: ""},
+	}
+
+	for _, test := range tests {
+		current, _ := url.Parse(test.url)
+		doc, _ := goquery.NewDocumentFromReader(strings.NewReader("<html></html>"))
+		next := getPageType(current).next(current, doc, test.count)
+		result := ""
+		if next != nil {
+			result = next.String()
+		}
+		if result != test.expected {
+			t.Errorf("next(%s) = %s, expected %s", test.url, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package imdb
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/lightglitch/seekerr/provider"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	idRegex   = regexp.MustCompile(`tt\d+`)
+	yearRegex = regexp.MustCompile(`\d{4}`)
+	// the position in the titles of the charts, like "1. The Godfather"
+	positionRegex = regexp.MustCompile(`^\d+\.\s+`)
+)
+
+// markup has the selectors of the items of a version of the imdb pages, the
+// title is the text of the link when not set.
+type markup struct {
+	name  string
+	items string
+	link  string
+	title string
+	year  string
+}
+
+var (
+	// the lists and search results before the redesign
+	listerMarkup = markup{
+		name:  "lister",
+		items: ".lister-list .lister-item",
+		link:  ".lister-item-header a",
+		year:  ".lister-item-header .lister-item-year",
+	}
+	// the charts before the redesign
+	chartMarkup = markup{
+		name:  "chart",
+		items: "table.chart tbody tr",
+		link:  "td.titleColumn a",
+		year:  "td.titleColumn .secondaryInfo",
+	}
+	// the redesigned lists, charts, watchlists and search results
+	summaryMarkup = markup{
+		name:  "summary",
+		items: "li.ipc-metadata-list-summary-item",
+		link:  "a.ipc-title-link-wrapper",
+		title: "h3.ipc-title__text",
+		year:  ".cli-title-metadata-item, .dli-title-metadata-item",
+	}
+)
+
+// pageType is a kind of imdb page with its markups, tried in order, and the
+// query parameter of its pages, "page" for the page number or "start" for
+// the position of the first item.
+type pageType struct {
+	name    string
+	path    *regexp.Regexp
+	markups []markup
+	param   string
+}
+
+var pageTypes = []pageType{
+	{
+		name:    "chart",
+		path:    regexp.MustCompile(`^/chart/`),
+		markups: []markup{chartMarkup, summaryMarkup},
+	},
+	{
+		name:    "search",
+		path:    regexp.MustCompile(`^/search/title`),
+		markups: []markup{listerMarkup, summaryMarkup},
+		param:   "start",
+	},
+	{
+		name:    "watchlist",
+		path:    regexp.MustCompile(`^/(user/ur\d+/watchlist|list/watchlist)`),
+		markups: []markup{summaryMarkup, listerMarkup},
+		param:   "page",
+	},
+	{
+		name:    "list",
+		path:    regexp.MustCompile(`^/list/ls\d+`),
+		markups: []markup{listerMarkup, summaryMarkup},
+		param:   "page",
+	},
+}
+
+// otherPage is any other imdb page, only the first page is read.
+var otherPage = pageType{
+	name:    "other",
+	markups: []markup{listerMarkup, summaryMarkup, chartMarkup},
+}
+
+func getPageType(pageUrl *url.URL) pageType {
+	for _, pageType := range pageTypes {
+		if pageType.path.MatchString(pageUrl.Path) {
+			return pageType
+		}
+	}
+	return otherPage
+}
+
+// parse returns the items of the structured data of the page, or of the
+// first markup found when the page has none.
+func (t pageType) parse(doc *goquery.Document) ([]provider.ListItem, string) {
+	if items := nextDataItems(doc); len(items) > 0 {
+		return items, "next data"
+	}
+	if items := jsonLdItems(doc); len(items) > 0 {
+		return items, "json-ld"
+	}
+
+	for _, markup := range t.markups {
+		selection := doc.Find(markup.items)
+		if selection.Length() == 0 {
+			continue
+		}
+
+		items := []provider.ListItem{}
+		selection.Each(func(index int, s *goquery.Selection) {
+			link := s.Find(markup.link).First()
+			title := link.Text()
+			if markup.title != "" {
+				title = s.Find(markup.title).First().Text()
+			}
+			yearText := yearRegex.FindString(s.Find(markup.year).First().Text())
+			year, _ := strconv.Atoi(yearText)
+
+			items = append(items, provider.ListItem{
+				Title: positionRegex.ReplaceAllString(strings.TrimSpace(title), ""),
+				Year:  year,
+				Imdb:  idRegex.FindString(link.AttrOr("href", "")),
+			})
+		})
+		return items, markup.name
+	}
+	return []provider.ListItem{}, ""
+}
+
+// next returns the url of the page after the current, linked in the page or
+// with the page parameter, nil when the pages of the type aren't numbered.
+func (t pageType) next(current *url.URL, doc *goquery.Document, count int) *url.URL {
+	if href, ok := doc.Find("a.lister-page-next.next-page").First().Attr("href"); ok {
+		if next, err := current.Parse(href); err == nil {
+			return next
+		}
+	}
+	if t.param == "" {
+		return nil
+	}
+
+	query := current.Query()
+	value, _ := strconv.Atoi(query.Get(t.param))
+	if value < 1 {
+		value = 1
+	}
+	if t.param == "start" {
+		value += count
+	} else {
+		value++
+	}
+	query.Set(t.param, strconv.Itoa(value))
+
+	next := *current
+	next.RawQuery = query.Encode()
+	return &next
+}
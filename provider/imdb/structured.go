@@ -0,0 +1,194 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package imdb
+
+import (
+	"encoding/json"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/lightglitch/seekerr/provider"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var titleIdRegex = regexp.MustCompile(`^tt\d+$`)
+
+// nextDataItems returns the largest list of titles in the __NEXT_DATA__ of
+// the redesigned pages, the other lists are the recommendations and the
+// like. The path of the list changes with the type of page, so the data is
+// searched instead.
+func nextDataItems(doc *goquery.Document) []provider.ListItem {
+	script := doc.Find("script#__NEXT_DATA__").First()
+	if script.Length() == 0 {
+		return nil
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(script.Text()), &data); err != nil {
+		return nil
+	}
+
+	best := []provider.ListItem{}
+	walkLists(data, func(list []interface{}) {
+		items := []provider.ListItem{}
+		for _, element := range list {
+			if item, ok := nextDataTitle(element); ok {
+				items = append(items, item)
+			}
+		}
+		if len(items) > len(best) {
+			best = items
+		}
+	})
+	return best
+}
+
+func walkLists(value interface{}, visit func(list []interface{})) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(value) {
+			walkLists(value[key], visit)
+		}
+	case []interface{}:
+		visit(value)
+		for _, child := range value {
+			walkLists(child, visit)
+		}
+	}
+}
+
+// nextDataTitle returns the movie of the element of a list, the titles are
+// wrapped in the elements of some lists, like {"node": {"id": "tt..."}}.
+func nextDataTitle(value interface{}) (provider.ListItem, bool) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return provider.ListItem{}, false
+	}
+	if item, ok := nextDataMovie(object); ok {
+		return item, true
+	}
+	for _, key := range sortedKeys(object) {
+		if child, ok := object[key].(map[string]interface{}); ok {
+			if item, ok := nextDataMovie(child); ok {
+				return item, true
+			}
+		}
+	}
+	return provider.ListItem{}, false
+}
+
+func nextDataMovie(object map[string]interface{}) (provider.ListItem, bool) {
+	item := provider.ListItem{}
+	id, _ := object["id"].(string)
+	if id == "" {
+		id, _ = object["titleId"].(string)
+	}
+	if !titleIdRegex.MatchString(id) {
+		return item, false
+	}
+	item.Imdb = id
+
+	switch title := object["titleText"].(type) {
+	case string:
+		item.Title = title
+	case map[string]interface{}:
+		item.Title, _ = title["text"].(string)
+	}
+	switch year := object["releaseYear"].(type) {
+	case float64:
+		item.Year = int(year)
+	case map[string]interface{}:
+		if value, ok := year["year"].(float64); ok {
+			item.Year = int(value)
+		}
+	}
+
+	titleType := ""
+	switch value := object["titleType"].(type) {
+	case string:
+		titleType = value
+	case map[string]interface{}:
+		titleType, _ = value["id"].(string)
+	}
+	return item, item.Title != "" && !isSeries(titleType)
+}
+
+type jsonLdTitle struct {
+	Type          string `json:"@type"`
+	Url           string `json:"url"`
+	Name          string `json:"name"`
+	DatePublished string `json:"datePublished"`
+}
+
+type jsonLdList struct {
+	Type            string `json:"@type"`
+	ItemListElement []struct {
+		jsonLdTitle
+		Item *jsonLdTitle `json:"item"`
+	} `json:"itemListElement"`
+}
+
+// jsonLdItems returns the titles of the ItemList in the JSON-LD of the page.
+func jsonLdItems(doc *goquery.Document) []provider.ListItem {
+	items := []provider.ListItem{}
+	doc.Find(`script[type="application/ld+json"]`).Each(func(index int, s *goquery.Selection) {
+		list := jsonLdList{}
+		if err := json.Unmarshal([]byte(s.Text()), &list); err != nil || list.Type != "ItemList" {
+			return
+		}
+		for _, element := range list.ItemListElement {
+			title := element.jsonLdTitle
+			if element.Item != nil {
+				title = *element.Item
+			}
+			imdb := idRegex.FindString(title.Url)
+			if imdb == "" || title.Name == "" || isSeries(title.Type) {
+				continue
+			}
+			year, _ := strconv.Atoi(yearRegex.FindString(title.DatePublished))
+			items = append(items, provider.ListItem{
+				Title: html.UnescapeString(title.Name),
+				Year:  year,
+				Imdb:  imdb,
+			})
+		}
+	})
+	return items
+}
+
+// isSeries is true for the series and episodes, like tvSeries, TV Mini
+// Series or TVEpisode, that can't be added to radarr.
+func isSeries(titleType string) bool {
+	titleType = strings.ToLower(titleType)
+	return strings.Contains(titleType, "series") || strings.Contains(titleType, "episode")
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
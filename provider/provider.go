@@ -22,18 +22,49 @@
 
 package provider
 
+import "time"
+
 type ListType string
 
 const (
 	RSS   = "rss"
 	IMDB  = "imdb"
 	TRAKT = "trakt"
+	JSON  = "json"
+	CSV   = "csv"
+	FILE  = "file"
 )
 
 type ListFilter struct {
 	Limit    int
 	Exclude  []string
 	Revision []string
+	// the items matching these rules are added to the radarr exclusions
+	AutoExclude []string
+}
+
+const (
+	SYNC_UNMONITOR = "unmonitor"
+	SYNC_DELETE    = "delete"
+)
+
+// ListSync removes the movies added by the list that left it, without file,
+// after the grace period. Without action the movies are never removed.
+type ListSync struct {
+	Action      string
+	GracePeriod time.Duration
+	Exclude     bool
+}
+
+// ListFields maps the fields of the json and csv lists to the item, for json
+// they are paths like "ids.imdb" inside each item of the Items path, and for
+// csv the names of the columns.
+type ListFields struct {
+	Items string
+	Title string
+	Year  string
+	Imdb  string
+	Tmdb  string
 }
 
 type ListConfig struct {
@@ -41,6 +72,14 @@ type ListConfig struct {
 	Type    ListType
 	GuessIt bool
 	Filter  ListFilter
+	Sync    ListSync
+	Fields  ListFields
+	// only for the file lists, import the list when its files change
+	Watch bool
+	// only for the trakt lists, the minimum rating of the ratings lists and
+	// the filters of the movies, like genres or years
+	MinRating int
+	Params    map[string]string
 }
 
 type ListItem struct {
@@ -54,6 +93,19 @@ type ListItem struct {
 	Runtime      int
 	Ratings      Ratings
 	CountRatings int
+	// only for the rss releases parsed by guessit, the release with the
+	// highest resolution and all the releases of the movie in the feed
+	Release      Release
+	Releases     []Release
+	ReleaseCount int
+}
+
+type Release struct {
+	ScreenSize   string
+	Format       string
+	VideoCodec   string
+	AudioCodec   string
+	ReleaseGroup string
 }
 
 type Ratings struct {
@@ -66,6 +118,12 @@ type ListProvider interface {
 	GetItems(config ListConfig) ([]ListItem, error)
 }
 
+// DoneMarker is implemented by the providers that mark the processed items in
+// the source, so they aren't fetched again.
+type DoneMarker interface {
+	MarkDone(config ListConfig, items []ListItem) error
+}
+
 func NewProviderRegistry() *Registry {
 	return &Registry{
 		providers: map[ListType]ListProvider{},
@@ -24,10 +24,15 @@ package rss
 
 import (
 	"errors"
+	"fmt"
 	"github.com/go-resty/resty/v2"
+	"github.com/gosimple/slug"
 	"github.com/lightglitch/seekerr/provider"
 	"github.com/lightglitch/seekerr/services/guessit"
+	"github.com/lightglitch/seekerr/utils/http"
 	"github.com/rs/zerolog"
+	"strconv"
+	"strings"
 )
 import "github.com/mmcdole/gofeed"
 
@@ -55,51 +60,99 @@ func (p *Provider) GetItems(config provider.ListConfig) ([]provider.ListItem, er
 	}
 	result := []provider.ListItem{}
 
-	resp, err := p.restyClient.R().Get(config.Url)
+	resp, err := http.ServiceRequest(p.restyClient, "rss").Get(config.Url)
 
 	if err != nil {
 		p.logger.Error().Err(err).Msg("Fetching feed")
-		return []provider.ListItem{}, err
+		return []provider.ListItem{}, fmt.Errorf("fetching feed %s: %w", config.Url, err)
 	}
 
 	if resp.IsError() {
-		return []provider.ListItem{}, errors.New(resp.Status() + " - " + resp.String())
+		return []provider.ListItem{}, fmt.Errorf("fetching feed %s: %w", config.Url, errors.New(resp.Status()+" - "+resp.String()))
 	}
 
 	feed, err := p.parser.ParseString(resp.String())
 	if err != nil {
 		p.logger.Error().Err(err).Msg("Parsing feed")
-		return result, err
+		return result, fmt.Errorf("parsing feed %s: %w", config.Url, err)
 	}
 
 	p.logger.Info().Int("Count", len(feed.Items)).Msg("Found feed items.")
 
-	for index, item := range feed.Items {
+	positions := map[string]int{}
+	for _, item := range feed.Items {
 		p.logger.Debug().Interface("item", item).Msgf("Processing feed item %s.", item.Title)
 
 		if config.GuessIt && p.guessit != nil {
 			guessResult, err := p.guessit.GuessIt(item.Title)
+			if err != nil {
+				p.logger.Error().Err(err).Msgf("Guessing feed item %s.", item.Title)
+			}
 			if err == nil && guessResult != nil && guessResult.Type == "movie" {
 				p.logger.Info().Msgf("Guessed feed item %s (%d).", guessResult.Title, guessResult.Year)
-				result = append(result, provider.ListItem{
+				result = addRelease(result, positions, limit, groupKey(item.Title, guessResult), provider.ListItem{
 					Title: guessResult.Title,
 					Year:  guessResult.Year,
 					Imdb:  "",
+				}, &provider.Release{
+					ScreenSize:   guessResult.ScreenSize,
+					Format:       guessResult.Format,
+					VideoCodec:   guessResult.VideoCodec,
+					AudioCodec:   guessResult.AudioCodec,
+					ReleaseGroup: guessResult.ReleaseGroup,
 				})
 			}
 		} else {
-
-			result = append(result, provider.ListItem{
+			// the release name is matched later, the builtin parser only
+			// groups the releases of the same movie
+			result = addRelease(result, positions, limit, groupKey(item.Title, guessit.Parse(item.Title)), provider.ListItem{
 				Title: item.Title,
 				Year:  0,
 				Imdb:  "",
-			})
+			}, nil)
 		}
+	}
+
+	p.logger.Info().Int("Count", len(result)).Msg("Found feed movies.")
+	return result, nil
+}
+
+// resolution is the number of lines of the screen size, like 2160 of 2160p.
+func resolution(screenSize string) int {
+	value, _ := strconv.Atoi(strings.TrimRight(strings.ToLower(screenSize), "pi"))
+	return value
+}
+
+// groupKey is the key of the releases of the same movie, the name when the
+// title can't be parsed.
+func groupKey(name string, guessResult *guessit.GuessResult) string {
+	if guessResult == nil || guessResult.Title == "" {
+		return slug.Make(name)
+	}
+	return fmt.Sprintf("%s-%d", slug.Make(guessResult.Title), guessResult.Year)
+}
 
-		if index >= limit {
-			break
+// addRelease groups the releases of the same movie in one item, the feeds
+// have the same movie by several groups and resolutions. The release of the
+// item is the one with the highest resolution. The limit is of movies, the
+// releases of the movies already in the items are still grouped.
+func addRelease(items []provider.ListItem, positions map[string]int, limit int, key string, item provider.ListItem, release *provider.Release) []provider.ListItem {
+	position, ok := positions[key]
+	if !ok {
+		if len(items) >= limit {
+			return items
 		}
+		position = len(items)
+		positions[key] = position
+		items = append(items, item)
 	}
 
-	return result, nil
+	items[position].ReleaseCount++
+	if release != nil {
+		items[position].Releases = append(items[position].Releases, *release)
+		if len(items[position].Releases) == 1 || resolution(release.ScreenSize) > resolution(items[position].Release.ScreenSize) {
+			items[position].Release = *release
+		}
+	}
+	return items
 }
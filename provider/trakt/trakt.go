@@ -23,18 +23,44 @@
 package trakt
 
 import (
+	"errors"
+	"fmt"
 	"github.com/lightglitch/seekerr/provider"
 	"github.com/lightglitch/seekerr/services/trakt"
 	"github.com/rs/zerolog"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
 const (
 	TRAKT_URL_PROTOCOL = "trakt://"
-	TRAKT_URL_PREFIX   = "https://trakt.tv/users/"
+	TRAKT_URL_PREFIX   = "https://trakt.tv/"
 )
 
+// traktPaths maps the paths of the trakt site to the paths of the api, the
+// ratings lists can have the rating and the movies lists the period.
+var traktPaths = []struct {
+	pattern *regexp.Regexp
+	path    string
+}{
+	{regexp.MustCompile(`^users/([^/?]+)/lists/([^/?]+)`), "users/$1/lists/$2/items/movies"},
+	{regexp.MustCompile(`^users/([^/?]+)/watchlist`), "users/$1/watchlist/movies"},
+	{regexp.MustCompile(`^users/([^/?]+)/collection`), "users/$1/collection/movies"},
+	{regexp.MustCompile(`^users/([^/?]+)/ratings(?:/movies)?(?:/(\d+))?`), "users/$1/ratings/movies/$2"},
+	{regexp.MustCompile(`^movies/(watched|played|collected|recommended)(?:/(daily|weekly|monthly|yearly|all))?`), "movies/$1/$2"},
+	{regexp.MustCompile(`^movies/(trending|popular|anticipated|boxoffice)`), "movies/$1"},
+	{regexp.MustCompile(`^search(?:/movies?)?`), "search/movie"},
+}
+
+// filterParams are the params of the site urls passed to the api, the
+// filters of the movies lists and the query of the search.
+var filterParams = map[string]bool{
+	"query": true, "years": true, "genres": true, "languages": true, "countries": true,
+	"runtimes": true, "studio_ids": true, "ratings": true, "certifications": true,
+}
+
 func NewProvider(trakt *trakt.Client, logger *zerolog.Logger) *Provider {
 	return &Provider{
 		trakt:  trakt,
@@ -49,26 +75,27 @@ type Provider struct {
 
 func (p *Provider) GetItems(config provider.ListConfig) ([]provider.ListItem, error) {
 
+	if p.trakt == nil {
+		return []provider.ListItem{}, errors.New("missing trakt service configuration")
+	}
+
 	limit := config.Filter.Limit
 	if limit == 0 {
 		limit = 1000
 	}
 	result := []provider.ListItem{}
 
-	url := config.Url
-	if strings.HasPrefix(url, TRAKT_URL_PROTOCOL) {
-		url = trakt.TRAKT_URL + strings.TrimPrefix(url, TRAKT_URL_PROTOCOL)
+	listUrl, params, err := apiUrl(config)
+	if err != nil {
+		return result, err
 	}
-	if strings.HasPrefix(url, TRAKT_URL_PREFIX) {
-		user := strings.TrimPrefix(regexp.MustCompile(`/users/([^/]*)`).FindString(url), "/users/")
-		list := strings.TrimPrefix(regexp.MustCompile(`/lists/([^/?]*)`).FindString(url), "/lists/")
+	p.logger.Debug().Interface("params", params).Msgf("Finding list url %s", listUrl)
 
-		url = trakt.TRAKT_URL + "users/" + user + "/lists/" + list + "/items/movies"
-		p.logger.Debug().Msgf("Finding list url %s", url)
+	movies, err := p.trakt.FetchList(listUrl, params, limit)
+	if err != nil {
+		return result, fmt.Errorf("fetching trakt list %s: %w", config.Url, err)
 	}
 
-	movies, _ := p.trakt.FetchList(url, limit)
-
 	for _, item := range movies {
 		result = append(result, provider.ListItem{
 			Title: item.Title,
@@ -80,3 +107,63 @@ func (p *Provider) GetItems(config provider.ListConfig) ([]provider.ListItem, er
 
 	return result, nil
 }
+
+// ratings is the ratings filter of the api from the minimum rating, like
+// "8,9,10".
+func ratings(minRating int) string {
+	values := []string{}
+	for rating := minRating; rating <= 10; rating++ {
+		values = append(values, strconv.Itoa(rating))
+	}
+	return strings.Join(values, ",")
+}
+
+// apiUrl returns the api url and the query params of the trakt site url or
+// of the trakt:// shortcut, the api urls are used as they are. The filters in
+// the query of the url are kept and the params of the list override them.
+func apiUrl(config provider.ListConfig) (string, map[string]string, error) {
+	params := map[string]string{}
+
+	if !strings.HasPrefix(config.Url, TRAKT_URL_PROTOCOL) && !strings.HasPrefix(config.Url, TRAKT_URL_PREFIX) {
+		for key, value := range config.Params {
+			params[key] = value
+		}
+		return config.Url, params, nil
+	}
+
+	listUrl, err := url.Parse(config.Url)
+	if err != nil {
+		return "", params, fmt.Errorf("parsing trakt list url %s: %w", config.Url, err)
+	}
+	for key, values := range listUrl.Query() {
+		if filterParams[key] && len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+	for key, value := range config.Params {
+		params[key] = value
+	}
+
+	path := strings.TrimPrefix(strings.TrimPrefix(config.Url, TRAKT_URL_PROTOCOL), TRAKT_URL_PREFIX)
+	if index := strings.IndexAny(path, "?#"); index >= 0 {
+		path = path[:index]
+	}
+	path = strings.Trim(path, "/")
+
+	for _, traktPath := range traktPaths {
+		if match := traktPath.pattern.FindStringSubmatchIndex(path); match != nil {
+			apiPath := string(traktPath.pattern.ExpandString(nil, traktPath.path, path, match))
+			// the api filters the ratings, so the limit only counts the rated movies
+			if strings.HasSuffix(apiPath, "/ratings/movies/") && config.MinRating > 0 {
+				apiPath += ratings(config.MinRating)
+			}
+			return trakt.TRAKT_URL + strings.TrimSuffix(apiPath, "/"), params, nil
+		}
+	}
+
+	// the other shortcuts are paths of the api
+	if strings.HasPrefix(config.Url, TRAKT_URL_PROTOCOL) {
+		return trakt.TRAKT_URL + path, params, nil
+	}
+	return "", params, fmt.Errorf("unsupported trakt list url %s", config.Url)
+}
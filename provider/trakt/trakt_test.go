@@ -0,0 +1,79 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package trakt
+
+import (
+	"github.com/lightglitch/seekerr/provider"
+	"reflect"
+	"testing"
+)
+
+func TestApiUrl(t *testing.T) {
+	tests := []struct {
+		url       string
+		params    map[string]string
+		minRating int
+		expected  string
+		query     map[string]string
+	}{
+		{url: "trakt://movies/trending", expected: "https://api.trakt.tv/movies/trending"},
+		{url: "https://trakt.tv/movies/trending?genres=action&years=2020-2023&mode=media",
+			expected: "https://api.trakt.tv/movies/trending",
+			query:    map[string]string{"genres": "action", "years": "2020-2023"}},
+		{url: "https://trakt.tv/users/movistapp/lists/now-playing?sort=rank,asc",
+			expected: "https://api.trakt.tv/users/movistapp/lists/now-playing/items/movies"},
+		{url: "https://trakt.tv/users/sean/watchlist?sort=rank,asc",
+			expected: "https://api.trakt.tv/users/sean/watchlist/movies"},
+		{url: "https://trakt.tv/users/sean/collection/movies/added",
+			expected: "https://api.trakt.tv/users/sean/collection/movies"},
+		{url: "https://trakt.tv/users/sean/ratings/movies/all/added",
+			expected: "https://api.trakt.tv/users/sean/ratings/movies"},
+		{url: "https://trakt.tv/users/sean/ratings/movies/10",
+			expected: "https://api.trakt.tv/users/sean/ratings/movies/10"},
+		{url: "https://trakt.tv/users/sean/ratings", minRating: 8,
+			expected: "https://api.trakt.tv/users/sean/ratings/movies/8,9,10"},
+		{url: "https://trakt.tv/users/sean/ratings/movies/10", minRating: 8,
+			expected: "https://api.trakt.tv/users/sean/ratings/movies/10"},
+		{url: "trakt://movies/watched/monthly", expected: "https://api.trakt.tv/movies/watched/monthly"},
+		{url: "https://trakt.tv/movies/played", expected: "https://api.trakt.tv/movies/played"},
+		{url: "https://trakt.tv/movies/collected/yearly", expected: "https://api.trakt.tv/movies/collected/yearly"},
+		{url: "trakt://movies/recommended/all", params: map[string]string{"certifications": "pg-13,r", "ratings": "70-100"},
+			expected: "https://api.trakt.tv/movies/recommended/all",
+			query:    map[string]string{"certifications": "pg-13,r", "ratings": "70-100"}},
+		{url: "https://trakt.tv/search/movies?query=batman&countries=us", params: map[string]string{"countries": "gb"},
+			expected: "https://api.trakt.tv/search/movie",
+			query:    map[string]string{"query": "batman", "countries": "gb"}},
+		{url: "https://api.trakt.tv/movies/boxoffice", expected: "https://api.trakt.tv/movies/boxoffice"},
+		{url: "https://trakt.tv/shows/trending", expected: ""},
+	}
+
+	for _, test := range tests {
+		result, query, _ := apiUrl(provider.ListConfig{Url: test.url, Params: test.params, MinRating: test.minRating})
+		if test.query == nil {
+			test.query = map[string]string{}
+		}
+		if result != test.expected || !reflect.DeepEqual(query, test.query) {
+			t.Errorf("apiUrl(%s) = %s %v, expected %s %v", test.url, result, query, test.expected, test.query)
+		}
+	}
+}
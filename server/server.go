@@ -0,0 +1,173 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"github.com/lightglitch/seekerr/importer"
+	"github.com/lightglitch/seekerr/utils/metrics"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_LISTEN    = ":8090"
+	DEFAULT_CACHE_TTL = time.Hour
+)
+
+// ListMovie is the format of the Radarr custom and StevenLu lists, the id is
+// the tmdb id.
+type ListMovie struct {
+	Id        int    `json:"id,omitempty"`
+	ImdbId    string `json:"imdb_id,omitempty"`
+	Title     string `json:"title"`
+	Year      int    `json:"year,omitempty"`
+	PosterUrl string `json:"poster_url,omitempty"`
+}
+
+type cachedList struct {
+	movies  []ListMovie
+	expires time.Time
+}
+
+// NewServer creates the http server of the lists and metrics, the config is
+// the server section and may be nil.
+func NewServer(config *viper.Viper, logger *zerolog.Logger, importer *importer.Importer) *Server {
+	server := &Server{
+		logger:   logger.With().Str("Component", "Server").Logger(),
+		importer: importer,
+		listen:   DEFAULT_LISTEN,
+		cacheTtl: DEFAULT_CACHE_TTL,
+		cache:    map[string]cachedList{},
+	}
+	if config != nil {
+		if config.IsSet("listen") {
+			server.listen = config.GetString("listen")
+		}
+		if config.IsSet("cacheTtl") {
+			server.cacheTtl = config.GetDuration("cacheTtl")
+		}
+	}
+	return server
+}
+
+type Server struct {
+	logger   zerolog.Logger
+	importer *importer.Importer
+	listen   string
+	cacheTtl time.Duration
+	cache    map[string]cachedList
+	// the importer processes one list at a time
+	mutex sync.Mutex
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/lists", s.handleLists)
+	mux.HandleFunc("/lists/", s.handleList)
+	return mux
+}
+
+func (s *Server) ListenAndServe() error {
+	s.logger.Info().Str("listen", s.listen).Msg("Serving lists")
+	return http.ListenAndServe(s.listen, s.Handler())
+}
+
+func (s *Server) writeJson(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		s.logger.Error().Err(err).Msg("Writing response")
+	}
+}
+
+func (s *Server) handleLists(w http.ResponseWriter, r *http.Request) {
+	s.writeJson(w, http.StatusOK, s.importer.Lists())
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(strings.Trim(strings.TrimPrefix(r.URL.Path, "/lists/"), "/"))
+
+	found := false
+	for _, list := range s.importer.Lists() {
+		found = found || list == name
+	}
+	if !found {
+		s.writeJson(w, http.StatusNotFound, map[string]string{"error": "list not found"})
+		return
+	}
+
+	movies, err := s.getList(name)
+	if err != nil {
+		s.logger.Error().Err(err).Str("list", name).Msg("Processing list")
+		s.writeJson(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	s.writeJson(w, http.StatusOK, movies)
+}
+
+// getList returns the approved movies of the list, from the cache while it
+// is valid.
+func (s *Server) getList(name string) ([]ListMovie, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if cached, ok := s.cache[name]; ok && time.Now().Before(cached.expires) {
+		return cached.movies, nil
+	}
+
+	approved, err := s.importer.ApprovedItems(name)
+	if err != nil {
+		return nil, err
+	}
+
+	movies := []ListMovie{}
+	for _, movie := range approved {
+		result := ListMovie{
+			Id:     movie.Item.Tmdb,
+			ImdbId: movie.Item.Imdb,
+			Title:  movie.Item.Title,
+			Year:   movie.Item.Year,
+		}
+		if movie.Movie != nil {
+			result.Id = movie.Movie.TmdbID
+			result.Title = movie.Movie.Title
+			result.Year = movie.Movie.Year
+			result.PosterUrl = movie.Movie.GetPoster()
+			if movie.Movie.ImdbId != "" {
+				result.ImdbId = movie.Movie.ImdbId
+			}
+		}
+		movies = append(movies, result)
+	}
+
+	s.cache[name] = cachedList{movies: movies, expires: time.Now().Add(s.cacheTtl)}
+	return movies, nil
+}
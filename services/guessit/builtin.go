@@ -0,0 +1,252 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package guessit
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	containerPattern = regexp.MustCompile(`(?i)\.(mkv|mp4|avi|m4v|wmv|mov)$`)
+	bracketPattern   = regexp.MustCompile(`\s*\[([^\[\]]+)\]$`)
+	separatorPattern = regexp.MustCompile(`[\s._()\[\]]+`)
+	screenPattern    = regexp.MustCompile(`(?i)^(\d{3,4}[pi]|4k)$`)
+	episodePattern   = regexp.MustCompile(`(?i)^s(\d{1,2})e(\d{1,3})$|^(\d{1,2})x(\d{2,3})$`)
+	seasonPattern    = regexp.MustCompile(`(?i)^s(\d{1,2})$`)
+	audioPattern     = regexp.MustCompile(`(?i)^(ddp|dd\+|dd|eac3|e-ac-3|ac3|aac|dts-hd|dts-x|dts|truehd|atmos|flac|mp3|opus)([1-7])?$`)
+	channelsPattern  = regexp.MustCompile(`^[1-7]$`)
+)
+
+var sources = map[string]string{
+	"bluray": "BluRay", "blu-ray": "BluRay", "bdrip": "BluRay", "brrip": "BluRay", "bdremux": "BluRay",
+	"web-dl": "WEB-DL", "webdl": "WEB-DL", "webrip": "WEBRip", "web": "WEB",
+	"hdtv": "HDTV", "pdtv": "TV", "dvdrip": "DVD", "dvd": "DVD", "dvdr": "DVD", "dvdscr": "DVD",
+	"hdrip": "HDRip", "cam": "Cam", "hdcam": "Cam", "ts": "Telesync", "hdts": "Telesync", "telesync": "Telesync",
+}
+
+var videoCodecs = map[string]string{
+	"x264": "H.264", "h264": "H.264", "avc": "H.264",
+	"x265": "H.265", "h265": "H.265", "hevc": "H.265",
+	"xvid": "XviD", "divx": "DivX", "av1": "AV1",
+}
+
+var audioCodecs = map[string]string{
+	"dd": "Dolby Digital", "ac3": "Dolby Digital",
+	"ddp": "Dolby Digital Plus", "dd+": "Dolby Digital Plus", "eac3": "Dolby Digital Plus", "e-ac-3": "Dolby Digital Plus",
+	"aac": "AAC", "dts": "DTS", "dts-hd": "DTS-HD", "dts-x": "DTS:X",
+	"truehd": "Dolby TrueHD", "atmos": "Dolby Atmos", "flac": "FLAC", "mp3": "MP3", "opus": "Opus",
+}
+
+var mimeTypes = map[string]string{
+	"mkv": "video/x-matroska", "mp4": "video/mp4", "m4v": "video/mp4", "avi": "video/x-msvideo",
+	"wmv": "video/x-ms-wmv", "mov": "video/quicktime",
+}
+
+// the sources that are also common words
+var weakSources = map[string]bool{
+	"web": true, "cam": true, "ts": true, "dvd": true,
+}
+
+// the tags that aren't part of the title but aren't reported
+var otherTags = map[string]bool{
+	"proper": true, "repack": true, "rerip": true, "real": true, "internal": true, "limited": true,
+	"extended": true, "unrated": true, "uncut": true, "remastered": true, "theatrical": true, "imax": true,
+	"uhd": true, "hdr": true, "hdr10": true, "dv": true, "10bit": true, "3d": true, "remux": true, "ma": true,
+	"multi": true, "dubbed": true, "subbed": true, "complete": true,
+	"amzn": true, "nf": true, "dsnp": true, "hmax": true, "atvp": true,
+}
+
+func parseYear(token string) int {
+	if len(token) != 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(token)
+	if err != nil || year < 1900 || year > 2099 {
+		return 0
+	}
+	return year
+}
+
+func isCodecPair(tokens []string, index int) bool {
+	token := strings.ToLower(tokens[index])
+	return (token == "h" || token == "x") && index+1 < len(tokens) && (tokens[index+1] == "264" || tokens[index+1] == "265")
+}
+
+func isTechnical(tokens []string, index int) bool {
+	token := strings.ToLower(tokens[index])
+	if _, ok := sources[token]; ok {
+		return true
+	}
+	if _, ok := videoCodecs[token]; ok {
+		return true
+	}
+	return otherTags[token] || isCodecPair(tokens, index) || screenPattern.MatchString(token) ||
+		episodePattern.MatchString(token) || seasonPattern.MatchString(token) || audioPattern.MatchString(token)
+}
+
+// isStrong is true for the technical tokens that are never words of a title,
+// the titles can have words like web or proper.
+func isStrong(tokens []string, index int) bool {
+	token := strings.ToLower(tokens[index])
+	return isTechnical(tokens, index) && !otherTags[token] && !weakSources[token]
+}
+
+// isChannels is true for the audio channels split by the separators, like
+// the 5 and 1 of 5.1.
+func isChannels(tokens []string, index int) bool {
+	return index+1 < len(tokens) && (tokens[index+1] == "0" || tokens[index+1] == "1")
+}
+
+func splitTokens(name string) []string {
+	tokens := []string{}
+	for _, token := range separatorPattern.Split(name, -1) {
+		if token != "" && token != "-" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// splitGroup removes the release group of the end of the name, like the
+// SPARKS of "Movie.2019.1080p.BluRay.x264-SPARKS" or the [YTS.MX] suffix.
+func splitGroup(name string) (string, string) {
+	if match := bracketPattern.FindStringSubmatch(name); match != nil {
+		tokens := splitTokens(match[1])
+		technical := false
+		for index := range tokens {
+			technical = technical || isTechnical(tokens, index) || parseYear(tokens[index]) != 0
+		}
+		if !technical {
+			return strings.TrimRight(name[:len(name)-len(match[0])], " ._-"), match[1]
+		}
+	}
+
+	tail := name[strings.LastIndexAny(name, " ._)]")+1:]
+	if index := strings.LastIndex(tail, "-"); index > 0 && index < len(tail)-1 {
+		if tokens := splitTokens(tail); len(tokens) == 1 && !isTechnical(tokens, 0) {
+			return name[:len(name)-len(tail)+index], tail[index+1:]
+		}
+	}
+	return name, ""
+}
+
+// Parse guesses the title, year and technical info of a release name, it
+// doesn't need the guessit command or webservice.
+func Parse(name string) *GuessResult {
+	result := &GuessResult{Type: "movie"}
+
+	name = strings.TrimSpace(name)
+	if match := containerPattern.FindStringSubmatch(name); match != nil {
+		result.Container = strings.ToLower(match[1])
+		result.MimeType = mimeTypes[result.Container]
+		name = name[:len(name)-len(match[0])]
+	}
+	name, result.ReleaseGroup = splitGroup(name)
+	tokens := splitTokens(name)
+
+	// the title goes until the last year before the technical tokens, or
+	// the first technical token without year. The first token is always
+	// part of it, like in "2001 A Space Odyssey 1968"
+	strong := len(tokens)
+	for index := 1; index < len(tokens); index++ {
+		if isStrong(tokens, index) {
+			strong = index
+			break
+		}
+	}
+	end := 0
+	for index := strong - 1; index > 0 && end == 0; index-- {
+		if year := parseYear(tokens[index]); year != 0 {
+			result.Year = year
+			end = index
+		}
+	}
+	start := end + 1
+	if end == 0 {
+		end = len(tokens)
+		for index := 1; index < len(tokens); index++ {
+			if isTechnical(tokens, index) {
+				end = index
+				break
+			}
+		}
+		start = end
+	}
+	result.Title = strings.Join(tokens[:end], " ")
+
+	for index := start; index < len(tokens); index++ {
+		token := strings.ToLower(tokens[index])
+
+		if match := episodePattern.FindStringSubmatch(token); match != nil {
+			season, episode := match[1]+match[3], match[2]+match[4]
+			result.Season, _ = strconv.ParseInt(season, 10, 64)
+			result.EpisodeNumber, _ = strconv.ParseInt(episode, 10, 64)
+			result.Type = "episode"
+		} else if match := seasonPattern.FindStringSubmatch(token); match != nil {
+			result.Season, _ = strconv.ParseInt(match[1], 10, 64)
+			result.Type = "episode"
+		} else if screenPattern.MatchString(token) {
+			if result.ScreenSize == "" {
+				result.ScreenSize = token
+				if token == "4k" {
+					result.ScreenSize = "2160p"
+				}
+			}
+		} else if source, ok := sources[token]; ok {
+			if result.Format == "" {
+				result.Format = source
+			}
+		} else if codec, ok := videoCodecs[token]; ok {
+			if result.VideoCodec == "" {
+				result.VideoCodec = codec
+			}
+		} else if isCodecPair(tokens, index) {
+			if result.VideoCodec == "" {
+				result.VideoCodec = videoCodecs["h"+tokens[index+1]]
+			}
+			index++
+		} else if match := audioPattern.FindStringSubmatch(token); match != nil {
+			if result.AudioCodec == "" {
+				result.AudioCodec = audioCodecs[match[1]]
+			}
+			if match[2] != "" && isChannels(tokens, index) {
+				if result.AudioChannels == "" {
+					result.AudioChannels = match[2] + "." + tokens[index+1]
+				}
+				index++
+			}
+		} else if channelsPattern.MatchString(token) && isChannels(tokens, index) {
+			if result.AudioChannels == "" {
+				result.AudioChannels = token + "." + tokens[index+1]
+			}
+			index++
+		}
+	}
+
+	if result.Type == "episode" {
+		result.Series = result.Title
+	}
+	return result
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package guessit
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected GuessResult
+	}{
+		{
+			name: "Blade.Runner.2049.2017.1080p.BluRay.x264-SPARKS",
+			expected: GuessResult{Title: "Blade Runner 2049", Year: 2017, Type: "movie", ScreenSize: "1080p",
+				Format: "BluRay", VideoCodec: "H.264", ReleaseGroup: "SPARKS"},
+		},
+		{
+			name: "2001.A.Space.Odyssey.1968.2160p.UHD.BluRay.x265-TERMINAL",
+			expected: GuessResult{Title: "2001 A Space Odyssey", Year: 1968, Type: "movie", ScreenSize: "2160p",
+				Format: "BluRay", VideoCodec: "H.265", ReleaseGroup: "TERMINAL"},
+		},
+		{
+			name: "1917.2019.1080p.BluRay.x264-SPARKS",
+			expected: GuessResult{Title: "1917", Year: 2019, Type: "movie", ScreenSize: "1080p",
+				Format: "BluRay", VideoCodec: "H.264", ReleaseGroup: "SPARKS"},
+		},
+		{
+			name: "Dune.2021.1080p.WEB-DL.DDP5.1.Atmos.H.264-EVO",
+			expected: GuessResult{Title: "Dune", Year: 2021, Type: "movie", ScreenSize: "1080p", Format: "WEB-DL",
+				AudioCodec: "Dolby Digital Plus", AudioChannels: "5.1", VideoCodec: "H.264", ReleaseGroup: "EVO"},
+		},
+		{
+			name: "Parasite.2019.KOREAN.1080p.BluRay.x264.DTS-FGT",
+			expected: GuessResult{Title: "Parasite", Year: 2019, Type: "movie", ScreenSize: "1080p", Format: "BluRay",
+				VideoCodec: "H.264", AudioCodec: "DTS", ReleaseGroup: "FGT"},
+		},
+		{
+			name: "Nomadland.2020.720p.WEBRip.x264.AAC-[YTS.MX]",
+			expected: GuessResult{Title: "Nomadland", Year: 2020, Type: "movie", ScreenSize: "720p", Format: "WEBRip",
+				VideoCodec: "H.264", AudioCodec: "AAC", ReleaseGroup: "YTS.MX"},
+		},
+		{
+			name: "Knives Out (2019) [1080p] [BluRay] [5.1] [YTS.MX]",
+			expected: GuessResult{Title: "Knives Out", Year: 2019, Type: "movie", ScreenSize: "1080p", Format: "BluRay",
+				AudioChannels: "5.1", ReleaseGroup: "YTS.MX"},
+		},
+		{
+			name: "Spider-Man.No.Way.Home.2021.1080p.WEBRip.x264-RARBG",
+			expected: GuessResult{Title: "Spider-Man No Way Home", Year: 2021, Type: "movie", ScreenSize: "1080p",
+				Format: "WEBRip", VideoCodec: "H.264", ReleaseGroup: "RARBG"},
+		},
+		{
+			name: "Tenet.2020.IMAX.2160p.UHD.BluRay.x265.10bit.HDR.TrueHD.7.1.Atmos-SWTYBLZ",
+			expected: GuessResult{Title: "Tenet", Year: 2020, Type: "movie", ScreenSize: "2160p", Format: "BluRay",
+				VideoCodec: "H.265", AudioCodec: "Dolby TrueHD", AudioChannels: "7.1", ReleaseGroup: "SWTYBLZ"},
+		},
+		{
+			name: "The.Lord.of.the.Rings.The.Return.of.the.King.2003.EXTENDED.1080p.BluRay.x264-SiNNERS",
+			expected: GuessResult{Title: "The Lord of the Rings The Return of the King", Year: 2003, Type: "movie",
+				ScreenSize: "1080p", Format: "BluRay", VideoCodec: "H.264", ReleaseGroup: "SiNNERS"},
+		},
+		{
+			name: "Oppenheimer.2023.1080p.BluRay.DD5.1.x264-GalaxyRG.mkv",
+			expected: GuessResult{Title: "Oppenheimer", Year: 2023, Type: "movie", ScreenSize: "1080p", Format: "BluRay",
+				AudioCodec: "Dolby Digital", AudioChannels: "5.1", VideoCodec: "H.264", ReleaseGroup: "GalaxyRG",
+				Container: "mkv", MimeType: "video/x-matroska"},
+		},
+		{
+			name: "Charlottes.Web.2006.720p.BluRay.x264-SiNNERS",
+			expected: GuessResult{Title: "Charlottes Web", Year: 2006, Type: "movie", ScreenSize: "720p",
+				Format: "BluRay", VideoCodec: "H.264", ReleaseGroup: "SiNNERS"},
+		},
+		{
+			name: "Amelie.2001.FRENCH.DVDRip.XviD-BiPOLAR",
+			expected: GuessResult{Title: "Amelie", Year: 2001, Type: "movie", Format: "DVD", VideoCodec: "XviD",
+				ReleaseGroup: "BiPOLAR"},
+		},
+		{
+			name: "Top.Gun.Maverick.2022.1080p.WEB-DL",
+			expected: GuessResult{Title: "Top Gun Maverick", Year: 2022, Type: "movie", ScreenSize: "1080p",
+				Format: "WEB-DL"},
+		},
+		{
+			name: "The.Mandalorian.S02E05.1080p.WEB.H264-GLHF",
+			expected: GuessResult{Title: "The Mandalorian", Series: "The Mandalorian", Type: "episode", Season: 2,
+				EpisodeNumber: 5, ScreenSize: "1080p", Format: "WEB", VideoCodec: "H.264", ReleaseGroup: "GLHF"},
+		},
+		{
+			name: "Game.of.Thrones.S08E06.The.Iron.Throne.1080p.AMZN.WEB-DL.DDP5.1.H.264-GoT",
+			expected: GuessResult{Title: "Game of Thrones", Series: "Game of Thrones", Type: "episode", Season: 8,
+				EpisodeNumber: 6, ScreenSize: "1080p", Format: "WEB-DL", AudioCodec: "Dolby Digital Plus",
+				AudioChannels: "5.1", VideoCodec: "H.264", ReleaseGroup: "GoT"},
+		},
+		{
+			name:     "The Matrix 1999",
+			expected: GuessResult{Title: "The Matrix", Year: 1999, Type: "movie"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Parse(test.name)
+			if *result != test.expected {
+				t.Errorf("Parse(%q)\n got: %+v\nwant: %+v", test.name, *result, test.expected)
+			}
+		})
+	}
+}
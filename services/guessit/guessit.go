@@ -26,6 +26,7 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/utils/http"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 	"net/url"
@@ -35,6 +36,7 @@ import (
 const (
 	WEBSERVICE = "webservice"
 	COMMAND    = "command"
+	BUILTIN    = "builtin"
 )
 
 type GuessResult struct {
@@ -54,9 +56,22 @@ type GuessResult struct {
 	Year          int    `json:"year"`
 }
 
+// NewClient creates the client of the configured guessit, the builtin parser
+// is used without config or when the command or webservice aren't available.
 func NewClient(config *viper.Viper, logger *zerolog.Logger, restyClient *resty.Client) *Client {
 
+	builtin := &Client{
+		logger:      logger.With().Str("Component", "GuessIt").Logger(),
+		serviceType: BUILTIN,
+	}
+	if config == nil {
+		return builtin
+	}
+
 	serviceType := config.GetString("type")
+	if serviceType == BUILTIN {
+		return builtin
+	}
 	if serviceType == "" || (serviceType != WEBSERVICE && serviceType != COMMAND) {
 		logger.Error().Msg("Missing guessit type configuration.")
 		return nil
@@ -72,8 +87,8 @@ func NewClient(config *viper.Viper, logger *zerolog.Logger, restyClient *resty.C
 		// Test the url
 		_, err := url.Parse(config.GetString("url"))
 		if err != nil {
-			logger.Err(err)
-			return nil
+			logger.Warn().Err(err).Msg("Invalid GuessIt url, using the builtin parser")
+			return builtin
 		}
 	}
 
@@ -82,8 +97,8 @@ func NewClient(config *viper.Viper, logger *zerolog.Logger, restyClient *resty.C
 		cmd := exec.Command(path)
 		_, err := cmd.Output()
 		if err != nil {
-			logger.Log().Err(err).Msg("Testing GuessIt command")
-			return nil
+			logger.Warn().Err(err).Msg("Testing GuessIt command, using the builtin parser")
+			return builtin
 		}
 	}
 
@@ -105,7 +120,7 @@ type Client struct {
 }
 
 func (c *Client) initRequest() *resty.Request {
-	return c.restyClient.R().
+	return http.ServiceRequest(c.restyClient, "guessit").
 		SetHeaders(map[string]string{
 			"Content-Type": "application/json",
 		})
@@ -123,6 +138,8 @@ func (c *Client) guessItUsingWebService(title string) (*GuessResult, error) {
 	if resp != nil && resp.IsSuccess() {
 		result := resp.Result().(*GuessResult)
 		return result, nil
+	} else if resp != nil && resp.IsError() {
+		return nil, errors.New(resp.Status() + " - " + resp.String())
 	}
 
 	return nil, err
@@ -151,5 +168,8 @@ func (c *Client) GuessIt(title string) (*GuessResult, error) {
 	if c.serviceType == COMMAND {
 		return c.guessItUsingCommand(title)
 	}
+	if c.serviceType == BUILTIN {
+		return Parse(title), nil
+	}
 	return nil, errors.New("Service not found")
 }
@@ -25,8 +25,13 @@ package omdb
 import (
 	"errors"
 	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/utils/http"
+	"github.com/lightglitch/seekerr/utils/metrics"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -34,8 +39,21 @@ const (
 	OMDB_IMDB_SOURCE            = "Internet Movie Database"
 	OMDB_METACRITIC_SOURCE      = "Metacritic"
 	OMDB_ROTTEN_TOMATOES_SOURCE = "Rotten Tomatoes"
+	OMDB_DAILY_LIMIT            = 1000
+	OMDB_LIMIT_REACHED          = "Request limit reached"
 )
 
+// ErrNotFound is returned when omdb doesn't have the movie or series, like
+// the "Movie not found!" responses.
+var ErrNotFound = errors.New("not found in omdb")
+
+func resultError(message string) error {
+	if strings.HasSuffix(message, "not found!") {
+		return ErrNotFound
+	}
+	return errors.New(message)
+}
+
 type Result struct {
 	Title      string
 	Year       string
@@ -91,25 +109,73 @@ func NewClient(config *viper.Viper, logger *zerolog.Logger, restyClient *resty.C
 		return nil
 	}
 
+	dailyLimit := OMDB_DAILY_LIMIT
+	if config.IsSet("dailyLimit") {
+		dailyLimit = config.GetInt("dailyLimit")
+	}
+
 	c := &Client{
 		logger:      logger.With().Str("Component", "OMDB").Logger(),
 		url:         OMDB_URL,
 		restyClient: restyClient,
 		apiKey:      config.GetString("apiKey"),
+		dailyLimit:  dailyLimit,
 	}
 
+	c.reportQuota(false, false)
+
 	return c
 }
 
+// quota counts the requests of the day in the process, a client is created
+// for each import so the cron runs share it.
+var quota struct {
+	mutex sync.Mutex
+	day   string
+	used  int
+}
+
 type Client struct {
 	logger      zerolog.Logger
 	restyClient *resty.Client
 	url         string
 	apiKey      string
+	dailyLimit  int
+}
+
+// updateQuota estimates the requests left for the day, OMDb doesn't report it
+// so we count the requests made since the start and trust the limit error.
+func (c *Client) updateQuota(resp *resty.Response) {
+	c.reportQuota(true, resp != nil && strings.Contains(resp.String(), OMDB_LIMIT_REACHED))
+}
+
+// reportQuota counts the request, or only reports the remaining quota of the
+// day when there isn't one.
+func (c *Client) reportQuota(request bool, limitReached bool) {
+	quota.mutex.Lock()
+	defer quota.mutex.Unlock()
+
+	today := time.Now().UTC().Format("2006-01-02")
+	if quota.day != today {
+		quota.day = today
+		quota.used = 0
+	}
+	if request {
+		quota.used++
+	}
+	if limitReached {
+		quota.used = c.dailyLimit
+	}
+
+	remaining := c.dailyLimit - quota.used
+	if remaining < 0 {
+		remaining = 0
+	}
+	metrics.OmdbQuotaRemaining(remaining)
 }
 
 func (c *Client) initRequest() *resty.Request {
-	return c.restyClient.R().
+	return http.ServiceRequest(c.restyClient, "omdb").
 		SetHeaders(map[string]string{
 			"Content-Type": "application/json",
 		})
@@ -132,13 +198,17 @@ func (c *Client) GetMovieById(imdbId string, params map[string]string) (*Result,
 		SetResult(&Result{}).
 		Get(c.url)
 
+	c.updateQuota(resp)
+
 	if resp != nil && resp.IsSuccess() {
 		title := resp.Result().(*Result)
 		if title.Response == "False" {
 			c.logger.Error().Interface("result", title).Msg("Fetching movie info")
-			return nil, errors.New(title.Error)
+			return nil, resultError(title.Error)
 		}
 		return title, nil
+	} else if resp != nil && resp.IsError() {
+		err = errors.New(resp.Status() + " - " + resp.String())
 	}
 
 	c.logger.Error().Err(err).Msg("Fetching movie info")
@@ -162,13 +232,17 @@ func (c *Client) GetMovieByTitle(title string, params map[string]string) (*Resul
 		SetResult(&Result{}).
 		Get(c.url)
 
+	c.updateQuota(resp)
+
 	if resp != nil && resp.IsSuccess() {
 		title := resp.Result().(*Result)
 		if title.Response == "False" {
 			c.logger.Error().Err(errors.New(title.Error)).Msg("Fetching movie info")
-			return nil, errors.New(title.Error)
+			return nil, resultError(title.Error)
 		}
 		return title, nil
+	} else if resp != nil && resp.IsError() {
+		err = errors.New(resp.Status() + " - " + resp.String())
 	}
 
 	c.logger.Error().Err(err).Msg("Fetching movie info")
@@ -192,9 +266,13 @@ func (c *Client) SearchMovieByTitle(title string, params map[string]string) (*Se
 		SetResult(SearchResponse{}).
 		Get(c.url)
 
+	c.updateQuota(resp)
+
 	if resp != nil && resp.IsSuccess() {
 		search := resp.Result().(*SearchResponse)
 		return search, nil
+	} else if resp != nil && resp.IsError() {
+		err = errors.New(resp.Status() + " - " + resp.String())
 	}
 
 	return nil, err
@@ -218,13 +296,17 @@ func (c *Client) GetSeriesById(imdbId string, params map[string]string) (*Result
 		SetResult(&Result{}).
 		Get(c.url)
 
+	c.updateQuota(resp)
+
 	if resp != nil && resp.IsSuccess() {
 		title := resp.Result().(*Result)
 		if title.Response == "False" {
 			c.logger.Error().Interface("result", title).Msg("Fetching movie info")
-			return nil, errors.New(title.Error)
+			return nil, resultError(title.Error)
 		}
 		return title, nil
+	} else if resp != nil && resp.IsError() {
+		err = errors.New(resp.Status() + " - " + resp.String())
 	}
 
 	c.logger.Error().Err(err).Msg("Fetching movie info")
@@ -248,13 +330,17 @@ func (c *Client) GetSeriesByTitle(title string, params map[string]string) (*Resu
 		SetResult(&Result{}).
 		Get(c.url)
 
+	c.updateQuota(resp)
+
 	if resp != nil && resp.IsSuccess() {
 		title := resp.Result().(*Result)
 		if title.Response == "False" {
 			c.logger.Error().Err(errors.New(title.Error)).Msg("Fetching movie info")
-			return nil, errors.New(title.Error)
+			return nil, resultError(title.Error)
 		}
 		return title, nil
+	} else if resp != nil && resp.IsError() {
+		err = errors.New(resp.Status() + " - " + resp.String())
 	}
 
 	c.logger.Error().Err(err).Msg("Fetching movie info")
@@ -278,9 +364,13 @@ func (c *Client) SearchSeriesByTitle(title string, params map[string]string) (*S
 		SetResult(SearchResponse{}).
 		Get(c.url)
 
+	c.updateQuota(resp)
+
 	if resp != nil && resp.IsSuccess() {
 		search := resp.Result().(*SearchResponse)
 		return search, nil
+	} else if resp != nil && resp.IsError() {
+		err = errors.New(resp.Status() + " - " + resp.String())
 	}
 
 	return nil, err
@@ -24,10 +24,13 @@ package radarr
 
 import (
 	"errors"
+	"fmt"
 	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/utils/http"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -109,7 +112,9 @@ type Client struct {
 
 // Movie ...
 type Movie struct {
+	ID                  int    `json:"id,omitempty"`
 	Title               string `json:"title"`
+	OriginalTitle       string `json:"originalTitle,omitempty"`
 	TitleSlug           string `json:"titleSlug"`
 	Overview            string `json:"overview"`
 	QualityProfileID    int    `json:"qualityProfileId"`
@@ -123,10 +128,27 @@ type Movie struct {
 		CoverType string `json:"coverType"`
 		URL       string `json:"url"`
 	} `json:"images"`
+	AlternateTitles []struct {
+		Title string `json:"title"`
+	} `json:"alternateTitles,omitempty"`
 	IsAvailable bool                   `json:"isAvailable"`
+	HasFile     bool                   `json:"hasFile,omitempty"`
 	AddOptions  map[string]interface{} `json:"addOptions"`
 }
 
+// GetPoster returns the url of the poster image or the first image found.
+func (m *Movie) GetPoster() string {
+	for _, image := range m.Images {
+		if image.CoverType == "poster" {
+			return image.URL
+		}
+	}
+	if len(m.Images) > 0 {
+		return m.Images[0].URL
+	}
+	return ""
+}
+
 type ExcludedMovie struct {
 	ID         int    `json:"id"`
 	MovieTitle string `json:"movieTitle"`
@@ -150,7 +172,7 @@ func (c *Client) getEndpointUrl(endpoint string) string {
 }
 
 func (c *Client) initRequest() *resty.Request {
-	return c.restyClient.R().
+	return http.ServiceRequest(c.restyClient, "radarr").
 		SetHeaders(map[string]string{
 			"Content-Type": "application/json",
 			"X-Api-Key":    c.apiKey,
@@ -178,7 +200,7 @@ func (c *Client) validateApiKey() (bool, error) {
 		return ok, nil
 	}
 
-	return false, nil
+	return false, errors.New(resp.Status() + " - " + resp.String())
 }
 
 func (c *Client) getProfileId() (int, error) {
@@ -198,9 +220,10 @@ func (c *Client) getProfileId() (int, error) {
 				return int(profile["id"].(float64)), nil
 			}
 		}
+		return 0, fmt.Errorf("quality profile '%s' not found", c.quality)
 	}
 
-	return 0, nil
+	return 0, errors.New(resp.Status() + " - " + resp.String())
 }
 
 func (c *Client) AddMovie(movie *Movie) error {
@@ -222,6 +245,10 @@ func (c *Client) AddMovie(movie *Movie) error {
 		SetError(Errors{}).
 		Post(c.getEndpointUrl("api/v3/movie"))
 
+	if err != nil {
+		return err
+	}
+
 	if resp.StatusCode() == 400 {
 		message := ""
 		errorsList := resp.Error().(*Errors)
@@ -231,7 +258,33 @@ func (c *Client) AddMovie(movie *Movie) error {
 		return errors.New(message)
 	}
 
-	return err
+	if resp.IsError() {
+		return errors.New(resp.Status() + " - " + resp.String())
+	}
+
+	return nil
+}
+
+// LookupMovie searches the movies by the term, like the title and year.
+func (c *Client) LookupMovie(term string) (*[]Movie, error) {
+
+	resp, err := c.
+		initRequest().
+		SetQueryParams(map[string]string{
+			"term": term,
+		}).
+		SetResult([]Movie{}).
+		Get(c.getEndpointUrl("api/v3/movie/lookup"))
+
+	if resp != nil && resp.IsSuccess() {
+		c.logger.Debug().RawJSON("response", []byte(resp.String())).Msg("Lookup search")
+		result := resp.Result().(*[]Movie)
+		return result, nil
+	} else if resp != nil && resp.IsError() {
+		return nil, errors.New(resp.Status() + " - " + resp.String())
+	}
+
+	return nil, err
 }
 
 func (c *Client) LookupMovieByImdb(imdbId string) (*Movie, error) {
@@ -248,6 +301,8 @@ func (c *Client) LookupMovieByImdb(imdbId string) (*Movie, error) {
 		c.logger.Debug().RawJSON("response", []byte(resp.String())).Msg("Lookup search")
 		result := resp.Result().(*Movie)
 		return result, nil
+	} else if resp != nil && resp.IsError() {
+		return nil, errors.New(resp.Status() + " - " + resp.String())
 	}
 
 	return nil, err
@@ -267,6 +322,8 @@ func (c *Client) LookupMovieByTmdb(tmdbId string) (*Movie, error) {
 		c.logger.Debug().RawJSON("response", []byte(resp.String())).Msg("Lookup search")
 		result := resp.Result().(*Movie)
 		return result, nil
+	} else if resp != nil && resp.IsError() {
+		return nil, errors.New(resp.Status() + " - " + resp.String())
 	}
 
 	return nil, err
@@ -305,3 +362,67 @@ func (c *Client) GetExcludedMovies() (*[]ExcludedMovie, error) {
 
 	return nil, err
 }
+
+// SetMonitored changes only the monitored flag of the movie, with the movie
+// editor, so the other settings are kept.
+func (c *Client) SetMonitored(id int, monitored bool) error {
+
+	resp, err := c.
+		initRequest().
+		SetBody(map[string]interface{}{
+			"movieIds":  []int{id},
+			"monitored": monitored,
+		}).
+		Put(c.getEndpointUrl("api/v3/movie/editor"))
+
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return errors.New(resp.Status() + " - " + resp.String())
+	}
+	return nil
+}
+
+// DeleteMovie removes the movie from radarr, keeping the files, and adds it
+// to the import exclusions when exclude is set.
+func (c *Client) DeleteMovie(id int, exclude bool) error {
+
+	resp, err := c.
+		initRequest().
+		SetQueryParams(map[string]string{
+			"deleteFiles":        "false",
+			"addImportExclusion": strconv.FormatBool(exclude),
+		}).
+		Delete(c.getEndpointUrl(fmt.Sprintf("api/v3/movie/%d", id)))
+
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return errors.New(resp.Status() + " - " + resp.String())
+	}
+	return nil
+}
+
+// AddExclusion adds the movie to the import exclusions, so it isn't added by
+// seekerr or the radarr lists.
+func (c *Client) AddExclusion(movie *Movie) error {
+
+	resp, err := c.
+		initRequest().
+		SetBody(ExcludedMovie{
+			MovieTitle: movie.Title,
+			MovieYear:  movie.Year,
+			TmdbID:     movie.TmdbID,
+		}).
+		Post(c.getEndpointUrl("api/v3/exclusions"))
+
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return errors.New(resp.Status() + " - " + resp.String())
+	}
+	return nil
+}
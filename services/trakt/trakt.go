@@ -23,7 +23,10 @@
 package trakt
 
 import (
+	"errors"
+	"fmt"
 	"github.com/go-resty/resty/v2"
+	"github.com/lightglitch/seekerr/utils/http"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 	"strconv"
@@ -89,7 +92,7 @@ type Item struct {
 }
 
 func (c *Client) initRequest() *resty.Request {
-	return c.restyClient.R().
+	return http.ServiceRequest(c.restyClient, "trakt").
 		SetHeaders(map[string]string{
 			"Content-Type":      "application/json",
 			"trakt-api-version": "2",
@@ -111,6 +114,8 @@ func (c *Client) fetchShowItemPagedList(url string, queryParams map[string]strin
 
 	if resp != nil && resp.IsSuccess() {
 		result = *resp.Result().(*[]ShowItem)
+	} else if resp != nil && resp.IsError() {
+		err = errors.New(resp.Status() + " - " + resp.String())
 	}
 
 	return result, err
@@ -130,6 +135,8 @@ func (c *Client) fetchMovieItemPagedList(url string, queryParams map[string]stri
 
 	if resp != nil && resp.IsSuccess() {
 		result = *resp.Result().(*[]MovieItem)
+	} else if resp != nil && resp.IsError() {
+		err = errors.New(resp.Status() + " - " + resp.String())
 	}
 
 	return result, err
@@ -149,13 +156,19 @@ func (c *Client) fetchItemPagedList(url string, queryParams map[string]string) (
 
 	if resp != nil && resp.IsSuccess() {
 		result = *resp.Result().(*[]Item)
+	} else if resp != nil && resp.IsError() {
+		err = errors.New(resp.Status() + " - " + resp.String())
 	}
 
 	return result, err
 }
 
-func (c *Client) FetchList(url string, limit int) ([]Item, error) {
+// FetchList fetches the movies of the list page by page until the limit,
+// the params are added to the query, like the filters of the movies lists.
+func (c *Client) FetchList(url string, params map[string]string, limit int) ([]Item, error) {
 	result := []Item{}
+	// the lists without pagination return every item on each page
+	seen := map[int]bool{}
 
 	page := 1
 	pageLimit := TRAKT_PAGE_LIMIT
@@ -166,35 +179,53 @@ func (c *Client) FetchList(url string, limit int) ([]Item, error) {
 
 	for ok := true; ok; ok = len(result) < limit && currentCount == pageLimit {
 
-		params := map[string]string{
+		queryParams := map[string]string{
 			"page":  strconv.Itoa(page),
 			"limit": strconv.Itoa(pageLimit),
 		}
+		for key, value := range params {
+			queryParams[key] = value
+		}
 
 		items := []Item{}
-		if strings.HasSuffix(url, "/movies/popular") || strings.Contains(url, "/movies/recommended/") {
+		if strings.HasSuffix(url, "/movies/popular") {
 			var err error = nil
-			items, err = c.fetchItemPagedList(url, params)
+			items, err = c.fetchItemPagedList(url, queryParams)
 
 			if err != nil {
-				c.logger.Error().Err(err).Interface("params", params).Msg("Fetching paged movies")
+				c.logger.Error().Err(err).Interface("params", queryParams).Msg("Fetching paged movies")
+				return result, fmt.Errorf("fetching trakt list %s page %d: %w", url, page, err)
 			}
 		} else {
-			movieItems, err := c.fetchMovieItemPagedList(url, params)
+			movieItems, err := c.fetchMovieItemPagedList(url, queryParams)
 
 			if err != nil {
-				c.logger.Error().Err(err).Interface("params", params).Msg("Fetching paged movies")
+				c.logger.Error().Err(err).Interface("params", queryParams).Msg("Fetching paged movies")
+				return result, fmt.Errorf("fetching trakt list %s page %d: %w", url, page, err)
 			}
-			currentCount = len(movieItems)
-			if currentCount > 0 {
-				for _, item := range movieItems {
-					items = append(items, item.Movie)
+			for _, item := range movieItems {
+				// the shows of the watchlists and search results
+				if item.Movie.IDs.Trakt == 0 && item.Movie.Title == "" {
+					continue
 				}
+				items = append(items, item.Movie)
 			}
 		}
 		currentCount = len(items)
-		if currentCount > 0 {
-			result = append(result, items...)
+
+		added := 0
+		for _, item := range items {
+			if item.IDs.Trakt != 0 && seen[item.IDs.Trakt] {
+				continue
+			}
+			seen[item.IDs.Trakt] = true
+			if len(result) < limit {
+				result = append(result, item)
+				added++
+			}
+		}
+		if added == 0 {
+			break
 		}
 
 		page++
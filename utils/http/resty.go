@@ -23,28 +23,50 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"github.com/go-resty/resty/v2"
 	"github.com/lightglitch/seekerr/utils/logger"
+	"github.com/lightglitch/seekerr/utils/metrics"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 	"net/http"
+	"strconv"
 	"time"
 )
 
+const (
+	UNKNOWN_SERVICE = "unknown"
+)
+
+type serviceKey struct{}
+
+// ServiceRequest creates a request tagged with the name of the external service, used in the metrics.
+func ServiceRequest(client *resty.Client, service string) *resty.Request {
+	return client.R().SetContext(context.WithValue(context.Background(), serviceKey{}, service))
+}
+
+func getService(r *resty.Request) string {
+	if service, ok := r.Context().Value(serviceKey{}).(string); ok {
+		return service
+	}
+	return UNKNOWN_SERVICE
+}
+
 type restyLogger struct {
 	logger *zerolog.Logger
 }
 
 func (l *restyLogger) Errorf(format string, v ...interface{}) {
-	l.logger.Error().Msgf(format, v)
+	l.logger.Error().Msgf(format, v...)
 }
 
 func (l *restyLogger) Warnf(format string, v ...interface{}) {
-	l.logger.Warn().Msgf(format, v)
+	l.logger.Warn().Msgf(format, v...)
 }
 
 func (l *restyLogger) Debugf(format string, v ...interface{}) {
-	l.logger.Debug().Msgf(format, v)
+	l.logger.Debug().Msgf(format, v...)
 }
 
 func GetRestyClient(config *viper.Viper) *resty.Client {
@@ -70,6 +92,20 @@ func GetRestyClient(config *viper.Viper) *resty.Client {
 		return r.StatusCode() == http.StatusTooManyRequests
 	})
 
+	c.OnAfterResponse(func(c *resty.Client, r *resty.Response) error {
+		metrics.HttpRequest(getService(r.Request), strconv.Itoa(r.StatusCode()), r.Time(), r.IsError())
+		return nil
+	})
+
+	c.OnError(func(r *resty.Request, err error) {
+		var respErr *resty.ResponseError
+		if errors.As(err, &respErr) {
+			metrics.HttpRequest(getService(r), strconv.Itoa(respErr.Response.StatusCode()), respErr.Response.Time(), true)
+			return
+		}
+		metrics.HttpError(getService(r), time.Since(r.Time))
+	})
+
 	//TODO support rate limit in the future
 
 	return c
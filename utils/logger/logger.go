@@ -48,7 +48,12 @@ func InitLogger() {
 		zerolog.SetGlobalLevel(level)
 	}
 
-	consoleWriter := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: viper.GetString("logger.time_format"), NoColor: !viper.GetBool("logger.color")}
+	out := os.Stdout
+	if viper.GetBool("logger.stderr") {
+		out = os.Stderr
+	}
+
+	consoleWriter := zerolog.ConsoleWriter{Out: out, TimeFormat: viper.GetString("logger.time_format"), NoColor: !viper.GetBool("logger.color")}
 	multi := zerolog.MultiLevelWriter(consoleWriter)
 
 	if viper.GetString("logger.file") != "" {
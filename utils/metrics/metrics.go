@@ -0,0 +1,123 @@
+/*
+ * Copyright © 2020 Mário Franco
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ */
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+const (
+	NAMESPACE = "seekerr"
+
+	DECISION_EXISTING      = "existing"
+	DECISION_EXCLUDED      = "excluded"
+	DECISION_AUTO_EXCLUDED = "auto_excluded"
+	DECISION_APPROVED      = "approved"
+	DECISION_REJECTED      = "rejected"
+	DECISION_REVISION      = "revision"
+	DECISION_ADDED         = "added"
+	DECISION_ERROR         = "error"
+)
+
+var (
+	itemsFetched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "list_items_fetched_total",
+		Help:      "Number of items fetched from the lists.",
+	}, []string{"list", "provider"})
+
+	itemDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "list_item_decisions_total",
+		Help:      "Number of list items by importer decision.",
+	}, []string{"list", "decision"})
+
+	lastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "list_last_success_timestamp_seconds",
+		Help:      "Unix time of the last run of the list without errors.",
+	}, []string{"list"})
+
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "http_requests_total",
+		Help:      "Number of http requests to external services.",
+	}, []string{"service", "code"})
+
+	httpErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "http_errors_total",
+		Help:      "Number of failed http requests to external services.",
+	}, []string{"service"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the http requests to external services.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service"})
+
+	omdbQuota = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "omdb_quota_remaining",
+		Help:      "Estimated number of OMDb requests left for the current day.",
+	})
+)
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func ItemsFetched(list string, provider string, count int) {
+	itemsFetched.WithLabelValues(list, provider).Add(float64(count))
+}
+
+func ItemDecision(list string, decision string) {
+	itemDecisions.WithLabelValues(list, decision).Inc()
+}
+
+func ListSucceeded(list string) {
+	lastSuccess.WithLabelValues(list).SetToCurrentTime()
+}
+
+// HttpRequest records a request that got a response, failed is true for error status codes.
+func HttpRequest(service string, code string, duration time.Duration, failed bool) {
+	httpRequests.WithLabelValues(service, code).Inc()
+	httpDuration.WithLabelValues(service).Observe(duration.Seconds())
+	if failed {
+		httpErrors.WithLabelValues(service).Inc()
+	}
+}
+
+// HttpError records a request that didn't get any response.
+func HttpError(service string, duration time.Duration) {
+	HttpRequest(service, "", duration, true)
+}
+
+func OmdbQuotaRemaining(remaining int) {
+	omdbQuota.Set(float64(remaining))
+}